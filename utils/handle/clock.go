@@ -0,0 +1,176 @@
+package handle
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时钟接口，封装对系统时间的依赖，测试时可替换为FakeClock
+type Clock interface {
+	// Now 当前时间
+	Now() time.Time
+	// Sleep 等待d时长
+	Sleep(d time.Duration)
+	// After 等待d时长后向返回的channel发送当前时间
+	After(d time.Duration) <-chan time.Time
+	// NewTimer 新建定时器
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 定时器接口，语义同time.Timer
+type Timer interface {
+	// C 定时器到期时接收时间的channel
+	C() <-chan time.Time
+	// Stop 停止定时器，定时器已到期或已停止返回false
+	Stop() bool
+	// Reset 重置定时器到期时长，定时器已到期或已停止返回false
+	Reset(d time.Duration) bool
+}
+
+// NewRealClock 新建基于系统时间的时钟
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return &realTimer{timer: time.NewTimer(d)} }
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time        { return t.timer.C }
+func (t *realTimer) Stop() bool                 { return t.timer.Stop() }
+func (t *realTimer) Reset(d time.Duration) bool { return t.timer.Reset(d) }
+
+// FakeClock 可控时钟，时间只在调用Advance或Sleep时前进，用于单元测试
+// Sleep不会阻塞，而是直接把时间推进d，并记录每次等待的时长
+type FakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	sleeps []time.Duration
+}
+
+// NewFakeClock 新建可控时钟，初始时间为now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now 当前时间
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Sleep 记录等待时长并把时间推进d，不阻塞
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.lock.Lock()
+	c.sleeps = append(c.sleeps, d)
+	c.lock.Unlock()
+	c.Advance(d)
+}
+
+// Sleeps 返回历次Sleep的时长
+func (c *FakeClock) Sleeps() []time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+// After 时间推进d后向返回的channel发送时间
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer 新建定时器，时间推进到期后触发
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.schedule(t, d)
+	return t
+}
+
+// Advance 把时间推进d，并触发所有已到期的定时器
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.ch <- c.now:
+		default: // 与time.Timer一致，channel已满时丢弃
+		}
+	}
+	c.timers = pending
+}
+
+// Timers 返回尚未到期的定时器数量，便于测试等待协程进入阻塞
+func (c *FakeClock) Timers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.timers)
+}
+
+// schedule 调用方需持有锁
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		return
+	}
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+}
+
+// remove 调用方需持有锁
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, item := range c.timers {
+		if item == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	active := t.clock.remove(t)
+	t.clock.schedule(t, d)
+	return active
+}
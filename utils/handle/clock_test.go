@@ -0,0 +1,147 @@
+package handle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestDoWithRetryWhenSomeErrsClock(t *testing.T) {
+	errFatal := errors.New("fatal error")
+	tests := []struct {
+		name       string
+		errs       []error // 每次调用h返回的错误，超出后返回nil
+		retryCount int
+		interval   time.Duration
+		wantErr    error
+		wantCalls  int
+		wantSleeps []time.Duration
+	}{
+		{name: "success", retryCount: 3, interval: time.Second, wantCalls: 1},
+		{name: "retry then success", errs: []error{errTest, errTest}, retryCount: 3, interval: time.Second,
+			wantCalls: 3, wantSleeps: []time.Duration{time.Second, time.Second}},
+		{name: "exceed retry count", errs: []error{errTest, errTest, errTest}, retryCount: 3,
+			interval: time.Second, wantErr: errTest, wantCalls: 3,
+			wantSleeps: []time.Duration{time.Second, time.Second}},
+		{name: "no retry error", errs: []error{errTest, errFatal}, retryCount: 3, interval: time.Second,
+			wantErr: errFatal, wantCalls: 2, wantSleeps: []time.Duration{time.Second}},
+		{name: "default interval", errs: []error{errTest}, retryCount: 2, interval: -1, wantCalls: 2,
+			wantSleeps: []time.Duration{200 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(0, 0)
+			clock := NewFakeClock(start)
+			calls := 0
+			h := func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}
+			needRetry := func(err error) bool { return errors.Is(err, errTest) }
+			err := DoWithRetryWhenSomeErrsClock(clock, h, needRetry, tt.retryCount, tt.interval)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", sleeps, tt.wantSleeps)
+			}
+			var total time.Duration
+			for _, d := range tt.wantSleeps {
+				total += d
+			}
+			if elapsed := clock.Now().Sub(start); elapsed != total {
+				t.Errorf("elapsed = %v, want %v", elapsed, total)
+			}
+		})
+	}
+}
+
+func fired(timer Timer) bool {
+	select {
+	case <-timer.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeClockTimer(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+	if clock.Timers() != 1 {
+		t.Fatalf("timers = %d, want 1", clock.Timers())
+	}
+	clock.Advance(999 * time.Millisecond)
+	if fired(timer) {
+		t.Fatal("timer fired before deadline")
+	}
+	clock.Advance(time.Millisecond)
+	select {
+	case now := <-timer.C():
+		if !now.Equal(time.Unix(1, 0)) {
+			t.Errorf("fired at %v, want %v", now, time.Unix(1, 0))
+		}
+	default:
+		t.Fatal("timer not fired at deadline")
+	}
+	if timer.Stop() {
+		t.Error("Stop on expired timer returned true")
+	}
+	if timer.Reset(time.Second) {
+		t.Error("Reset on expired timer returned true")
+	}
+	if !timer.Stop() {
+		t.Error("Stop on active timer returned false")
+	}
+	clock.Advance(time.Hour)
+	if fired(timer) {
+		t.Error("stopped timer fired")
+	}
+	if clock.Timers() != 0 {
+		t.Errorf("timers = %d, want 0", clock.Timers())
+	}
+}
+
+func TestFakeClockReset(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+	if !timer.Reset(2 * time.Second) {
+		t.Error("Reset on active timer returned false")
+	}
+	clock.Advance(time.Second)
+	if fired(timer) {
+		t.Fatal("reset timer fired at old deadline")
+	}
+	clock.Advance(time.Second)
+	if !fired(timer) {
+		t.Fatal("reset timer not fired at new deadline")
+	}
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("After(0) not fired immediately")
+	}
+	ch := clock.After(time.Minute)
+	clock.Sleep(time.Minute)
+	select {
+	case <-ch:
+	default:
+		t.Error("After not fired by Sleep")
+	}
+	if sleeps := clock.Sleeps(); !reflect.DeepEqual(sleeps, []time.Duration{time.Minute}) {
+		t.Errorf("sleeps = %v, want [1m]", sleeps)
+	}
+}
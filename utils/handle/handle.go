@@ -5,14 +5,29 @@ import (
 	"time"
 )
 
+// defaultClock 默认使用系统时间
+var defaultClock = NewRealClock()
+
 // DoWithRetry 运行handle函数，发生错误时重试
 func DoWithRetry(h func() error, retryCount int, interval time.Duration) error {
+	return DoWithRetryClock(defaultClock, h, retryCount, interval)
+}
+
+// DoWithRetryClock 运行handle函数，发生错误时重试，重试间隔通过clock等待
+func DoWithRetryClock(clock Clock, h func() error, retryCount int, interval time.Duration) error {
 	alwayRetry := func(e error) bool { return true }
-	return DoWithRetryWhenSomeErrs(h, alwayRetry, retryCount, interval)
+	return DoWithRetryWhenSomeErrsClock(clock, h, alwayRetry, retryCount, interval)
 }
 
 // DoWithRetryWhenSomeErrs 运行handle函数，仅特定错误时重试
 func DoWithRetryWhenSomeErrs(h func() error, needRetry func(error) bool, retryCount int, interval time.Duration) error {
+	return DoWithRetryWhenSomeErrsClock(defaultClock, h, needRetry, retryCount, interval)
+}
+
+// DoWithRetryWhenSomeErrsClock 运行handle函数，仅特定错误时重试，重试间隔通过clock等待
+// 测试时传入FakeClock，可不消耗真实时间验证重试次数和间隔
+func DoWithRetryWhenSomeErrsClock(clock Clock, h func() error, needRetry func(error) bool,
+	retryCount int, interval time.Duration) error {
 	if interval < 0 {
 		interval = time.Millisecond * 200 // 默认重试时间间隔
 	}
@@ -24,7 +39,7 @@ func DoWithRetryWhenSomeErrs(h func() error, needRetry func(error) bool, retryCo
 		if i == retryCount || !needRetry(e) { // 超过重试次数或者无需重试
 			return e
 		}
-		time.Sleep(interval)
+		clock.Sleep(interval)
 	}
 	return e
 }
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HMAC签名使用的请求头
+const (
+	HeaderAuthKey       = "X-Auth-Key"       // 密钥ID
+	HeaderAuthTimestamp = "X-Auth-Timestamp" // 签名时间，unix秒
+	HeaderAuthSignature = "X-Auth-Signature" // 签名，十六进制
+)
+
+var (
+	tokenRefreshBefore = time.Minute      // OAuth2 token在过期前多久刷新
+	tokenTimeout       = 10 * time.Second // 获取OAuth2 token的超时时间
+)
+
+// Authenticator 鉴权接口，在每次请求发送前设置鉴权信息，重试时会重新调用
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc 函数形式的Authenticator
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate 实现Authenticator接口
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// OptionWithAuth 设置鉴权方式，作为拦截器添加，需要在OptionWithRetry之后添加才能在每次重试时重新签名
+func OptionWithAuth(auth Authenticator) option {
+	return func(c *client) error {
+		c.middlewares = append(c.middlewares, func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				if err := auth.Authenticate(req); err != nil {
+					return nil, fmt.Errorf("authenticate fail, err: %v", err)
+				}
+				return next(req)
+			}
+		})
+		return nil
+	}
+}
+
+// NewBearerAuth 固定token鉴权，设置Authorization: Bearer token
+func NewBearerAuth(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// NewBasicAuth HTTP Basic鉴权
+func NewBasicAuth(username, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// NewHMACAuth HMAC-SHA256请求签名，待签名字符串为
+// METHOD\nPATH?QUERY\nHEX(SHA256(BODY))\nTIMESTAMP，
+// 签名结果及keyID、timestamp分别写入X-Auth-Signature、X-Auth-Key、X-Auth-Timestamp请求头
+func NewHMACAuth(keyID string, secret []byte) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		bodyHash, err := hashBody(req)
+		if err != nil {
+			return err
+		}
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		path := req.URL.EscapedPath()
+		if req.URL.RawQuery != "" {
+			path += "?" + req.URL.RawQuery
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(strings.Join([]string{req.Method, path, bodyHash, ts}, "\n")))
+		req.Header.Set(HeaderAuthKey, keyID)
+		req.Header.Set(HeaderAuthTimestamp, ts)
+		req.Header.Set(HeaderAuthSignature, hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	})
+}
+
+// hashBody 计算请求体的sha256，请求体不可重放时读取后替换为可重放的请求体
+func hashBody(req *http.Request) (string, error) {
+	h := sha256.New()
+	if req.Body == nil || req.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	if req.GetBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", fmt.Errorf("read body fail, err: %v", err)
+		}
+		req.Body.Close()
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", fmt.Errorf("get body fail, err: %v", err)
+	}
+	defer body.Close()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("hash body fail, err: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewOAuth2Auth OAuth2 client credentials鉴权，token缓存在内存中，过期前自动刷新
+func NewOAuth2Auth(tokenURL, clientID, clientSecret string, scopes ...string) Authenticator {
+	return &oauth2Auth{
+		httpClient:   &http.Client{Timeout: tokenTimeout},
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
+
+type oauth2Auth struct {
+	httpClient   *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse RFC 6749 token响应
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Authenticate 实现Authenticator接口
+func (a *oauth2Auth) Authenticate(req *http.Request) error {
+	token, err := a.getToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// getToken 返回缓存的token，即将过期时重新获取
+func (a *oauth2Auth) getToken(ctx context.Context) (string, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.token != "" && time.Now().Add(tokenRefreshBefore).Before(a.expiry) {
+		return a.token, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("new token request fail, err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+	rsp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request token fail, err: %v", err)
+	}
+	defer rsp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(rsp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read token rsp fail, err: %v", err)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return "", newStatusError(rsp, body)
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", fmt.Errorf("unmarshal token rsp fail, err: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return "", fmt.Errorf("token rsp has no access_token")
+	}
+	a.token = tr.AccessToken
+	a.expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	if tr.ExpiresIn <= 0 { // 未返回有效期时每次请求前都刷新过于频繁，按一小时处理
+		a.expiry = time.Now().Add(time.Hour)
+	}
+	return a.token, nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// 负载均衡算法名称
+const (
+	BalancerRoundRobin     = "round_robin"     // 轮询
+	BalancerWeightedRandom = "weighted_random" // 加权随机，权重取实例元数据MetadataWeight，需在注册时写入元数据
+	BalancerP2C            = "p2c"             // 随机选两个节点，取延迟与并发数综合最低者
+)
+
+// MetadataWeight 实例权重的元数据key，kratos nacos注册中心不会把nacos实例的Weight字段返回给服务发现，
+// 加权随机需要在注册时把权重写入ServiceInstance.Metadata，nacos包的RegisterServer会同步设置为nacos实例权重
+const MetadataWeight = "weight"
+
+var (
+	defaultWeight   float64 = 100              // 实例元数据未配置权重时的默认权重，所有实例权重相同即为均匀随机
+	ejectFailures   int64   = 3                // 连续失败多少次后剔除节点
+	ejectDuration           = 30 * time.Second // 节点被剔除的时长
+	latencyDecay            = 0.8              // 延迟滑动平均中历史值的占比
+	initialLatency          = int64(time.Millisecond)
+	errNoAvailNodes         = fmt.Errorf("no available nodes")
+)
+
+// node 服务实例节点及其统计信息
+type node struct {
+	addr     string
+	weight   float64
+	inflight int64 // 正在处理的请求数
+	latency  int64 // 延迟滑动平均，单位纳秒
+	fails    int64 // 连续失败次数
+	ejected  int64 // 剔除截止时间，unix纳秒
+}
+
+func newNode(addr string, weight float64) *node {
+	return &node{addr: addr, weight: weight, latency: initialLatency}
+}
+
+// available 节点是否未被剔除
+func (n *node) available(now time.Time) bool {
+	return atomic.LoadInt64(&n.ejected) <= now.UnixNano()
+}
+
+// start 请求开始，返回请求结束时的回调
+func (n *node) start() func(err error) {
+	begin := time.Now()
+	atomic.AddInt64(&n.inflight, 1)
+	return func(err error) {
+		atomic.AddInt64(&n.inflight, -1)
+		cost := int64(time.Since(begin))
+		old := atomic.LoadInt64(&n.latency)
+		atomic.StoreInt64(&n.latency, int64(float64(old)*latencyDecay+float64(cost)*(1-latencyDecay)))
+		if err == nil {
+			atomic.StoreInt64(&n.fails, 0)
+			return
+		}
+		if atomic.AddInt64(&n.fails, 1) >= ejectFailures {
+			atomic.StoreInt64(&n.fails, 0)
+			atomic.StoreInt64(&n.ejected, time.Now().Add(ejectDuration).UnixNano())
+		}
+	}
+}
+
+// score p2c打分，越小越好
+func (n *node) score() float64 {
+	return float64(atomic.LoadInt64(&n.latency)) * float64(atomic.LoadInt64(&n.inflight)+1)
+}
+
+// balancer 负载均衡器，从可用节点中选出一个
+type balancer interface {
+	pick(nodes []*node) *node
+}
+
+// newBalancer 按名称新建负载均衡器
+func newBalancer(name string) (balancer, error) {
+	switch name {
+	case BalancerRoundRobin:
+		return &roundRobin{}, nil
+	case BalancerWeightedRandom:
+		return weightedRandom{}, nil
+	case BalancerP2C:
+		return p2c{}, nil
+	}
+	return nil, fmt.Errorf("unsupport balancer %s", name)
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+func (b *roundRobin) pick(nodes []*node) *node {
+	i := atomic.AddUint64(&b.next, 1)
+	return nodes[(i-1)%uint64(len(nodes))]
+}
+
+type weightedRandom struct{}
+
+func (weightedRandom) pick(nodes []*node) *node {
+	var total float64
+	for _, n := range nodes {
+		total += n.weight
+	}
+	if total <= 0 {
+		return nodes[rand.Intn(len(nodes))]
+	}
+	r := rand.Float64() * total
+	for _, n := range nodes {
+		if r < n.weight {
+			return n
+		}
+		r -= n.weight
+	}
+	return nodes[len(nodes)-1]
+}
+
+type p2c struct{}
+
+func (p2c) pick(nodes []*node) *node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	a := rand.Intn(len(nodes))
+	b := rand.Intn(len(nodes) - 1)
+	if b >= a {
+		b++
+	}
+	if nodes[b].score() < nodes[a].score() {
+		return nodes[b]
+	}
+	return nodes[a]
+}
@@ -0,0 +1,318 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderFromCache 响应来自缓存时设置该响应头，可通过CallWithResponse查看
+const HeaderFromCache = "X-From-Cache"
+
+// maxCacheBodySize 超过该大小的响应体不缓存
+var maxCacheBodySize int64 = 8 * 1024 * 1024
+
+// CacheEntry 缓存的响应
+type CacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time // 存储或最近一次验证的时间
+	VaryNames  []string  // 非空时表示这是Vary索引，真正的响应按Vary请求头的值另存
+}
+
+// size 估算占用的字节数
+func (e *CacheEntry) size() int64 {
+	n := int64(len(e.Body))
+	for k, vs := range e.Header {
+		n += int64(len(k))
+		for _, v := range vs {
+			n += int64(len(v))
+		}
+	}
+	for _, v := range e.VaryNames {
+		n += int64(len(v))
+	}
+	return n
+}
+
+// Cache 响应缓存存储
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// OptionWithCache 开启GET响应缓存，遵循Cache-Control、Expires，过期后使用If-None-Match、If-Modified-Since验证；
+// 缓存按地址在所有调用间共享，携带Authorization的请求只有响应声明public或s-maxage时才缓存，避免串用其他调用方的响应；
+// 需要在OptionWithRetry之前添加，缓存命中时不再进入重试拦截器
+func OptionWithCache(cache Cache) option {
+	return func(c *client) error {
+		c.middlewares = append(c.middlewares, cacheMiddleware(cache))
+		return nil
+	}
+}
+
+func cacheMiddleware(cache Cache) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			key := req.URL.String()
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return invalidate(cache, key, next, req)
+			}
+			reqCC := parseCacheControl(req.Header.Get("Cache-Control"))
+			if req.Method != http.MethodGet || reqCC.has("no-store") {
+				return next(req)
+			}
+			entry, entryKey := lookup(cache, key, req.Header)
+			if entry != nil && !reqCC.has("no-cache") && fresh(entry, time.Now()) {
+				return entry.response(req), nil
+			}
+			if entry != nil { // 过期或要求验证，带上验证条件
+				req = req.Clone(req.Context())
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if lm := entry.Header.Get("Last-Modified"); lm != "" {
+					req.Header.Set("If-Modified-Since", lm)
+				}
+			}
+			rsp, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil && rsp.StatusCode == http.StatusNotModified {
+				rsp.Body.Close()
+				updated := *entry // 缓存项可能被并发读取，复制后再更新
+				updated.Header = entry.Header.Clone()
+				for k, v := range rsp.Header { // 用304的响应头更新缓存
+					updated.Header[k] = v
+				}
+				updated.StoredAt = time.Now()
+				cache.Set(entryKey, &updated)
+				return updated.response(req), nil
+			}
+			return store(cache, key, req, rsp), nil
+		}
+	}
+}
+
+// invalidate 非安全方法请求成功后删除该地址的缓存
+func invalidate(cache Cache, key string, next RoundTripFunc, req *http.Request) (*http.Response, error) {
+	rsp, err := next(req)
+	if err == nil && rsp.StatusCode < http.StatusBadRequest {
+		cache.Delete(key)
+	}
+	return rsp, err
+}
+
+// lookup 查找缓存，返回缓存项和其实际的key
+func lookup(cache Cache, key string, header http.Header) (*CacheEntry, string) {
+	entry, ok := cache.Get(key)
+	if !ok {
+		return nil, ""
+	}
+	if len(entry.VaryNames) == 0 {
+		return entry, key
+	}
+	key = varyKey(key, entry.VaryNames, header)
+	if entry, ok = cache.Get(key); !ok {
+		return nil, ""
+	}
+	return entry, key
+}
+
+// store 响应可缓存时读取响应体并存储，返回可继续读取的响应
+func store(cache Cache, key string, req *http.Request, rsp *http.Response) *http.Response {
+	cc := parseCacheControl(rsp.Header.Get("Cache-Control"))
+	if rsp.StatusCode != http.StatusOK || cc.has("no-store") || rsp.Header.Get("Vary") == "*" {
+		return rsp
+	}
+	if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") {
+		return rsp // 带认证的响应默认只属于该调用方，不能放入共享缓存
+	}
+	if _, ok := cc["max-age"]; !ok && rsp.Header.Get("Expires") == "" &&
+		rsp.Header.Get("ETag") == "" && rsp.Header.Get("Last-Modified") == "" {
+		return rsp // 既无有效期也无法验证，缓存没有意义
+	}
+	if rsp.ContentLength > maxCacheBodySize {
+		return rsp
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(rsp.Body, maxCacheBodySize+1))
+	if err != nil || int64(len(body)) > maxCacheBodySize { // 读取失败或过大时不缓存，剩余内容继续透传
+		rsp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), rsp.Body), Closer: rsp.Body}
+		return rsp
+	}
+	rsp.Body.Close()
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	entry := &CacheEntry{StatusCode: rsp.StatusCode, Header: rsp.Header.Clone(), Body: body, StoredAt: time.Now()}
+	if names := varyNames(rsp.Header); len(names) > 0 {
+		cache.Set(key, &CacheEntry{VaryNames: names})
+		key = varyKey(key, names, req.Header)
+	}
+	cache.Set(key, entry)
+	return rsp
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// fresh 缓存是否仍在有效期内
+func fresh(e *CacheEntry, now time.Time) bool {
+	cc := parseCacheControl(e.Header.Get("Cache-Control"))
+	if cc.has("no-cache") {
+		return false
+	}
+	age := now.Sub(e.StoredAt)
+	if v, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		age += time.Duration(v) * time.Second
+	}
+	if v, ok := cc["max-age"]; ok {
+		maxAge, err := strconv.Atoi(v)
+		return err == nil && age < time.Duration(maxAge)*time.Second
+	}
+	if expires, err := http.ParseTime(e.Header.Get("Expires")); err == nil {
+		return now.Before(expires)
+	}
+	return false
+}
+
+// response 用缓存构造响应
+func (e *CacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set(HeaderFromCache, "1")
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// varyNames 解析Vary响应头，返回规范化、排序后的请求头名称
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// varyKey 由地址和Vary请求头的值组成缓存key
+func varyKey(key string, names []string, header http.Header) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range names {
+		b.WriteString("\n" + name + ":" + strings.Join(header.Values(name), ","))
+	}
+	return b.String()
+}
+
+type cacheControl map[string]string
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// parseCacheControl 解析Cache-Control，指令名转为小写
+func parseCacheControl(v string) cacheControl {
+	cc := make(cacheControl)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value := part, ""
+		if i := strings.Index(part, "="); i >= 0 {
+			name, value = part[:i], strings.Trim(part[i+1:], `"`)
+		}
+		cc[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return cc
+}
+
+// NewLRUCache 新建内存LRU缓存，maxBytes为缓存响应的总大小上限
+func NewLRUCache(maxBytes int64) Cache {
+	return &lruCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+type lruCache struct {
+	lock     sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// Get 获取缓存并标记为最近使用
+func (c *lruCache) Get(key string) (*CacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, true
+}
+
+// Set 写入缓存，超过容量时淘汰最久未使用的项
+func (c *lruCache) Set(key string, entry *CacheEntry) {
+	size := entry.size() + int64(len(key))
+	if size > c.maxBytes {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: entry, size: size})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Delete 删除缓存
+func (c *lruCache) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// removeElement 调用方需持有锁
+func (c *lruCache) removeElement(elem *list.Element) {
+	item := elem.Value.(*lruItem)
+	c.ll.Remove(elem)
+	delete(c.items, item.key)
+	c.size -= item.size
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/form"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	_ "github.com/go-kratos/kratos/v2/encoding/xml"
+)
+
+// Codec 请求体、响应体编解码接口，复用kratos的codec注册表，
+// 自定义codec通过encoding.RegisterCodec注册后即可按名称使用
+type Codec = encoding.Codec
+
+// 内置codec名称
+const (
+	CodecJSON  = "json"
+	CodecForm  = "x-www-form-urlencoded"
+	CodecXML   = "xml"
+	CodecProto = "proto"
+	CodecRaw   = "octet-stream"
+)
+
+// subtypeAlias Content-Type子类型与codec名称不一致时的映射，解码响应时application/proto也能识别
+var subtypeAlias = map[string]string{
+	"x-protobuf": CodecProto,
+	"protobuf":   CodecProto,
+}
+
+// codecContentTypes Content-Type不是application/加codec名称的codec
+var codecContentTypes = map[string]string{
+	CodecProto: "application/x-protobuf",
+}
+
+func init() {
+	encoding.RegisterCodec(rawCodec{})
+}
+
+// getCodec 按名称获取已注册的codec
+func getCodec(name string) (Codec, error) {
+	codec := encoding.GetCodec(name)
+	if codec == nil {
+		return nil, fmt.Errorf("codec %s not registered", name)
+	}
+	return codec, nil
+}
+
+// contentType codec对应的Content-Type
+func contentType(codec Codec) string {
+	if ct, ok := codecContentTypes[codec.Name()]; ok {
+		return ct
+	}
+	return "application/" + codec.Name()
+}
+
+// codecForContentType 根据响应Content-Type选择codec，无法识别时返回nil
+func codecForContentType(ct string) Codec {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil
+	}
+	idx := strings.Index(mediaType, "/")
+	if idx < 0 {
+		return nil
+	}
+	subtype := mediaType[idx+1:]
+	if i := strings.LastIndex(subtype, "+"); i >= 0 { // 例如application/problem+json
+		subtype = subtype[i+1:]
+	}
+	if alias, ok := subtypeAlias[subtype]; ok {
+		subtype = alias
+	}
+	return encoding.GetCodec(subtype)
+}
+
+// rawCodec 原始字节codec，请求体支持[]byte和string，响应体支持*[]byte和*string
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	case string:
+		return []byte(b), nil
+	case *string:
+		return []byte(*b), nil
+	}
+	return nil, fmt.Errorf("raw codec unsupport type %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch b := v.(type) {
+	case *[]byte:
+		*b = append((*b)[:0], data...)
+		return nil
+	case *string:
+		*b = string(data)
+		return nil
+	}
+	return fmt.Errorf("raw codec unsupport type %T", v)
+}
+
+func (rawCodec) Name() string {
+	return CodecRaw
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		codec string
+		want  string
+	}{
+		{codec: CodecJSON, want: "application/json"},
+		{codec: CodecForm, want: "application/x-www-form-urlencoded"},
+		{codec: CodecXML, want: "application/xml"},
+		{codec: CodecProto, want: "application/x-protobuf"},
+		{codec: CodecRaw, want: "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := contentType(encoding.GetCodec(tt.codec)); got != tt.want {
+			t.Errorf("contentType(%s) = %s, want %s", tt.codec, got, tt.want)
+		}
+	}
+}
+
+func TestCodecForContentType(t *testing.T) {
+	tests := []struct {
+		ct   string
+		want string // 为空表示无法识别
+	}{
+		{ct: "application/json; charset=utf-8", want: CodecJSON},
+		{ct: "application/problem+json", want: CodecJSON},
+		{ct: "application/x-protobuf", want: CodecProto},
+		{ct: "application/protobuf", want: CodecProto},
+		{ct: "application/proto", want: CodecProto},
+		{ct: "text/xml", want: CodecXML},
+		{ct: "application/octet-stream", want: CodecRaw},
+		{ct: "text/html"},
+		{ct: ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if codec := codecForContentType(tt.ct); codec != nil {
+			got = codec.Name()
+		}
+		if got != tt.want {
+			t.Errorf("codecForContentType(%q) = %q, want %q", tt.ct, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 支持的压缩格式
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+var (
+	zstdEncoder     *zstd.Encoder
+	zstdEncoderOnce sync.Once
+	zstdEncoderErr  error
+)
+
+// OptionWithRequestCompression 请求体编码后不小于minSize字节时按encoding压缩，并设置Content-Encoding，
+// encoding支持EncodingGzip、EncodingZstd
+func OptionWithRequestCompression(encoding string, minSize int) option {
+	return func(c *client) error {
+		if encoding != EncodingGzip && encoding != EncodingZstd {
+			return fmt.Errorf("unsupport compression %s", encoding)
+		}
+		c.compression = encoding
+		c.compressMinSize = minSize
+		return nil
+	}
+}
+
+// OptionWithResponseDecompression 解压gzip、zstd、deflate响应体，请求未设置Accept-Encoding时声明支持gzip、zstd；
+// 标准库只在未手动设置Accept-Encoding时自动解压gzip，开启后自定义Accept-Encoding的响应也会被解压
+func OptionWithResponseDecompression() option {
+	return func(c *client) error {
+		c.middlewares = append(c.middlewares, decompressMiddleware)
+		return nil
+	}
+}
+
+// compress 按encoding压缩数据
+func compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingZstd:
+		zstdEncoderOnce.Do(func() {
+			zstdEncoder, zstdEncoderErr = zstd.NewWriter(nil)
+		})
+		if zstdEncoderErr != nil {
+			return nil, zstdEncoderErr
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unsupport compression %s", encoding)
+}
+
+func decompressMiddleware(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept-Encoding") == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Accept-Encoding", "gzip, zstd")
+		}
+		rsp, err := next(req)
+		if err != nil || !hasBody(req, rsp) {
+			return rsp, err
+		}
+		encoding := strings.ToLower(strings.TrimSpace(rsp.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			return rsp, nil
+		}
+		body, err := decompressReader(encoding, rsp.Body)
+		if err != nil {
+			rsp.Body.Close()
+			return nil, fmt.Errorf("decompress %s rsp fail, err: %v", encoding, err)
+		}
+		if body == nil { // 不支持的格式原样返回
+			return rsp, nil
+		}
+		rsp.Body = body
+		rsp.Header.Del("Content-Encoding")
+		rsp.Header.Del("Content-Length")
+		rsp.ContentLength = -1
+		rsp.Uncompressed = true
+		return rsp, nil
+	}
+}
+
+// hasBody 响应是否可能带响应体，HEAD、1xx、204、304及Content-Length为0的响应即使带Content-Encoding也没有响应体
+func hasBody(req *http.Request, rsp *http.Response) bool {
+	if req.Method == http.MethodHead || rsp.ContentLength == 0 {
+		return false
+	}
+	code := rsp.StatusCode
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// decompressReader 返回解压后的响应体，关闭时同时关闭原响应体，不支持的格式返回nil
+func decompressReader(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		r, err = gzip.NewReader(body)
+	case "deflate":
+		r, err = zlib.NewReader(body)
+	case EncodingZstd:
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(body); err == nil {
+			r = d.IOReadCloser()
+		}
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{Reader: r, Closer: multiCloser{r, body}}, nil
+}
+
+// multiCloser 依次关闭多个Closer，返回第一个错误
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
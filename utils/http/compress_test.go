@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestDecompressMiddleware(t *testing.T) {
+	gz, err := compress(EncodingGzip, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst, err := compress(EncodingZstd, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		method   string
+		code     int
+		encoding string
+		body     []byte
+		length   int64
+		want     string
+	}{
+		{name: "gzip", code: http.StatusOK, encoding: "gzip", body: gz, length: -1, want: "hello"},
+		{name: "zstd", code: http.StatusOK, encoding: "zstd", body: zst, length: -1, want: "hello"},
+		{name: "identity", code: http.StatusOK, encoding: "identity", body: []byte("hello"), length: 5, want: "hello"},
+		{name: "204", code: http.StatusNoContent, encoding: "gzip", length: -1},
+		{name: "304", code: http.StatusNotModified, encoding: "gzip", length: -1},
+		{name: "empty", code: http.StatusOK, encoding: "gzip", length: 0},
+		{name: "head", method: http.MethodHead, code: http.StatusOK, encoding: "gzip", length: 20},
+	}
+	for _, tt := range tests {
+		next := func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    tt.code,
+				Header:        http.Header{"Content-Encoding": []string{tt.encoding}},
+				Body:          io.NopCloser(bytes.NewReader(tt.body)),
+				ContentLength: tt.length,
+				Request:       req,
+			}, nil
+		}
+		method := tt.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, "http://example.com/", nil)
+		rsp, err := decompressMiddleware(next)(req)
+		if err != nil {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		got, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			t.Errorf("%s: read body err = %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/jensenguo/project-go/utils/coroutine"
+	"github.com/jensenguo/project-go/utils/handle"
+)
+
+// NewDiscoveryClient 新建基于服务发现的http client，通过discovery解析serviceName并监听实例变化，
+// 每次请求按负载均衡算法选择实例，连续失败的实例会被临时剔除；ctx取消后停止监听
+func NewDiscoveryClient(ctx context.Context, scheme, serviceName string, discovery registry.Discovery,
+	opts ...option) (Client, error) {
+	c, err := newClient(scheme, serviceName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newBalancer(c.balancer)
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{scheme: scheme, service: serviceName, balancer: b, clock: c.clock}
+	instances, err := discovery.GetService(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("get service %s fail, err: %v", serviceName, err)
+	}
+	r.update(instances)
+	watcher, err := discovery.Watch(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("watch service %s fail, err: %v", serviceName, err)
+	}
+	coroutine.Go(func() { r.watch(ctx, watcher) })
+	c.roundTrip = chain(c.middlewares, r.roundTrip(c.httpClient.Do))
+	return c, nil
+}
+
+// OptionWithBalancer 设置服务发现client的负载均衡算法，默认BalancerP2C
+func OptionWithBalancer(name string) option {
+	return func(c *client) error {
+		if _, err := newBalancer(name); err != nil {
+			return err
+		}
+		c.balancer = name
+		return nil
+	}
+}
+
+// resolver 维护服务实例节点，并为每次请求选择节点
+type resolver struct {
+	scheme   string
+	service  string
+	balancer balancer
+	clock    handle.Clock // 监听失败后的等待时钟，与client重试共用
+	lock     sync.RWMutex
+	nodes    []*node
+}
+
+// watch 监听实例变化直到ctx取消
+func (r *resolver) watch(ctx context.Context, watcher registry.Watcher) {
+	defer watcher.Stop()
+	for {
+		instances, err := watcher.Next()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Errorf("watch service %s fail, err: %v", r.service, err)
+			timer := r.clock.NewTimer(time.Second) // 避免注册中心异常时空转
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+			continue
+		}
+		r.update(instances)
+	}
+}
+
+// update 用最新实例列表替换节点，保留已有节点的统计信息
+func (r *resolver) update(instances []*registry.ServiceInstance) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	old := make(map[string]*node, len(r.nodes))
+	for _, n := range r.nodes {
+		old[n.addr] = n
+	}
+	nodes := make([]*node, 0, len(instances))
+	for _, ins := range instances {
+		addr, ok := r.endpoint(ins)
+		if !ok {
+			continue
+		}
+		weight := defaultWeight
+		if w, err := strconv.ParseFloat(ins.Metadata[MetadataWeight], 64); err == nil && w > 0 {
+			weight = w
+		}
+		if n, ok := old[addr]; ok {
+			n.weight = weight
+			nodes = append(nodes, n)
+			continue
+		}
+		nodes = append(nodes, newNode(addr, weight))
+	}
+	if len(nodes) == 0 && len(r.nodes) != 0 { // 推送空列表时保留旧节点，避免注册中心异常导致服务不可用
+		log.Errorf("service %s has no %s endpoints, keep old nodes", r.service, r.scheme)
+		return
+	}
+	r.nodes = nodes
+}
+
+// endpoint 获取实例中与client scheme一致的地址
+func (r *resolver) endpoint(ins *registry.ServiceInstance) (string, bool) {
+	for _, e := range ins.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			continue
+		}
+		if u.Scheme == r.scheme {
+			return u.Host, true
+		}
+	}
+	return "", false
+}
+
+// pick 从未被剔除的节点中选择，全部被剔除时退化为在所有节点中选择
+func (r *resolver) pick() (*node, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if len(r.nodes) == 0 {
+		return nil, errNoAvailNodes
+	}
+	now := time.Now()
+	avail := make([]*node, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		if n.available(now) {
+			avail = append(avail, n)
+		}
+	}
+	if len(avail) == 0 {
+		avail = r.nodes
+	}
+	return r.balancer.pick(avail), nil
+}
+
+// roundTrip 选择节点后替换请求地址，并根据结果更新节点统计
+func (r *resolver) roundTrip(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		n, err := r.pick()
+		if err != nil {
+			return nil, fmt.Errorf("pick node of %s fail, err: %v", r.service, err)
+		}
+		nreq := req.Clone(req.Context()) // 深拷贝Header和URL，不修改调用方的请求
+		nreq.URL.Host = n.addr
+		nreq.Host = n.addr
+		done := n.start()
+		rsp, err := next(nreq)
+		if err == nil && rsp.StatusCode >= http.StatusInternalServerError {
+			done(errors.New(rsp.Status))
+		} else if errors.Is(err, context.Canceled) { // 调用方取消不计入节点失败
+			done(nil)
+		} else {
+			done(err)
+		}
+		return rsp, err
+	}
+}
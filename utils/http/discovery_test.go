@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/jensenguo/project-go/utils/handle"
+)
+
+// errWatcher 每次Next都返回错误，记录调用次数
+type errWatcher struct {
+	calls chan struct{}
+}
+
+func (w *errWatcher) Next() ([]*registry.ServiceInstance, error) {
+	w.calls <- struct{}{}
+	return nil, errors.New("registry unavailable")
+}
+
+func (w *errWatcher) Stop() error {
+	return nil
+}
+
+func TestResolverWatchRetry(t *testing.T) {
+	clock := handle.NewFakeClock(time.Unix(0, 0))
+	r := &resolver{service: "test", clock: clock}
+	w := &errWatcher{calls: make(chan struct{}, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.watch(ctx, w)
+		close(done)
+	}()
+	<-w.calls
+	waitTimers(t, clock, 1)
+	clock.Advance(time.Second) // 等待1秒后再次监听
+	<-w.calls
+	waitTimers(t, clock, 1)
+	cancel() // 等待期间取消应立即返回，无需推进时钟
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watch not return after ctx cancel")
+	}
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("timers = %d, want 0", n)
+	}
+}
+
+func waitTimers(t *testing.T, clock *handle.FakeClock, n int) {
+	t.Helper()
+	for i := 0; i < 1000 && clock.Timers() != n; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := clock.Timers(); got != n {
+		t.Fatalf("timers = %d, want %d", got, n)
+	}
+}
+
+func TestResolverRoundTrip(t *testing.T) {
+	r := &resolver{scheme: "http", service: "test", balancer: mustBalancer(t, BalancerP2C)}
+	r.update([]*registry.ServiceInstance{{Endpoints: []string{"http://10.0.0.1:8000"}}})
+	req, _ := http.NewRequest(http.MethodGet, "http://test/users?id=1", nil)
+	req.Header.Set("X-Test", "1")
+	var got *http.Request
+	_, err := r.roundTrip(func(nreq *http.Request) (*http.Response, error) {
+		got = nreq
+		nreq.Header.Set("X-Test", "2") // 下游修改请求头不影响调用方
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Host != "10.0.0.1:8000" || got.Host != "10.0.0.1:8000" || got.URL.RawQuery != "id=1" {
+		t.Errorf("got url %s host %s, want host 10.0.0.1:8000", got.URL, got.Host)
+	}
+	if req.URL.Host != "test" || req.Header.Get("X-Test") != "1" {
+		t.Errorf("caller request modified: url %s header %s", req.URL, req.Header.Get("X-Test"))
+	}
+}
+
+func mustBalancer(t *testing.T, name string) balancer {
+	t.Helper()
+	b, err := newBalancer(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// maxErrorBodyLen StatusError中保留的响应体最大长度
+var maxErrorBodyLen = 1024
+
+// maxDrainBodyLen 关闭响应体前最多丢弃的字节数，超过时不再读取，放弃复用连接
+const maxDrainBodyLen = 64 << 10
+
+// ErrResponseTooLarge 响应体超过OptionWithMaxResponseSize设置的大小
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// StatusError 响应状态码非2xx时返回的错误，可通过errors.As获取
+type StatusError struct {
+	StatusCode int         // 响应状态码
+	Status     string      // 响应状态描述，例如"500 Internal Server Error"
+	Header     http.Header // 响应头
+	Body       []byte      // 响应体，超过maxErrorBodyLen会被截断
+	local      bool        // 是否由NewStatusError创建，WriteError只原样写入本服务创建的错误
+}
+
+// newStatusError 根据响应新建StatusError，body为已读取的响应体
+func newStatusError(rsp *http.Response, body []byte) *StatusError {
+	if len(body) > maxErrorBodyLen {
+		body = body[:maxErrorBodyLen]
+	}
+	return &StatusError{
+		StatusCode: rsp.StatusCode,
+		Status:     rsp.Status,
+		Header:     rsp.Header,
+		Body:       body,
+	}
+}
+
+// Error 实现error接口
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Retryable 该状态码是否可以重试
+func (e *StatusError) Retryable() bool {
+	return IsRetryableStatus(e.StatusCode)
+}
+
+// IsServerError 是否是服务端错误，即5xx
+func (e *StatusError) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// IsRetryableStatus 判断状态码是否可以重试，限流、网关错误、服务暂不可用、超时可以重试
+func IsRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
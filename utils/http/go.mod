@@ -1,3 +1,24 @@
 module github.com/jensenguo/project-go/utils/http
 
-go 1.17
+go 1.19
+
+require (
+	github.com/go-kratos/kratos/v2 v2.6.1
+	github.com/jensenguo/project-go/utils/coroutine v0.0.0-20230312043403-78ca9cf84ade
+	github.com/jensenguo/project-go/utils/handle v0.0.0-20261019145846-348d76175c79
+	github.com/klauspost/compress v1.17.4
+	go.opentelemetry.io/otel v1.17.0
+	go.opentelemetry.io/otel/metric v1.17.0
+	go.opentelemetry.io/otel/sdk v1.17.0
+	go.opentelemetry.io/otel/sdk/metric v0.40.0
+	go.opentelemetry.io/otel/trace v1.17.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-playground/form/v4 v4.2.0 // indirect
+	golang.org/x/sys v0.11.0 // indirect
+	google.golang.org/grpc v1.53.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
@@ -4,77 +4,372 @@ package http
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/jensenguo/project-go/utils/handle"
 )
 
 // Client http client接口定义
 type Client interface {
+	// Get 发送GET请求，query为查询参数
+	Get(ctx context.Context, path string, query url.Values, rsp interface{}, opts ...CallOption) error
 	// Post 发送POST请求
-	Post(ctx context.Context, path string, req, rsp interface{}) error
+	Post(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error
+	// Put 发送PUT请求
+	Put(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error
+	// Patch 发送PATCH请求
+	Patch(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error
+	// Delete 发送DELETE请求，query为查询参数
+	Delete(ctx context.Context, path string, query url.Values, rsp interface{}, opts ...CallOption) error
+	// Do 发送任意方法的请求，req为nil时不发送请求体，rsp为nil时忽略响应体
+	Do(ctx context.Context, method, path string, query url.Values, req, rsp interface{}, opts ...CallOption) error
+	// Stream 发送请求并返回响应体流，不受OptionWithMaxResponseSize限制，调用方负责Close
+	Stream(ctx context.Context, method, path string, query url.Values, req interface{},
+		opts ...CallOption) (io.ReadCloser, error)
+	// UploadMultipart 以multipart/form-data流式上传表单字段和文件
+	UploadMultipart(ctx context.Context, path string, fields map[string]string, files []*File, rsp interface{},
+		opts ...CallOption) error
+	// Download 下载文件写入w，返回本次写入的字节数，连接中断时通过Range续传，续传时文件已变化返回ErrResourceChanged
+	Download(ctx context.Context, path string, w io.Writer, opts ...CallOption) (int64, error)
+	// Subscribe 订阅Server-Sent Events，断线自动重连
+	Subscribe(ctx context.Context, path string, query url.Values, handle func(e *Event) error,
+		opts ...CallOption) error
 }
 
 type client struct {
 	httpClient *http.Client
+	transport  *http.Transport // httpClient使用的连接池
+	dialer     *net.Dialer     // transport建立连接使用的dialer
 	scheme     string
 	host       string
+	codec      Codec // 请求体编码格式，默认json
+	// middlewares 请求拦截器，roundTrip为串联拦截器后的请求函数
+	middlewares []Middleware
+	roundTrip   RoundTripFunc
+	balancer    string // 负载均衡算法，仅服务发现client使用
+	// maxResponseSize 响应体最大字节数，<=0不限制
+	maxResponseSize int64
+	// compression 请求体压缩格式，请求体不小于compressMinSize时压缩，为空不压缩
+	compression     string
+	compressMinSize int
+	// clock 重试及服务发现监听失败后等待使用的时钟，jitter为重试等待时间的抖动函数
+	clock  handle.Clock
+	jitter func(d time.Duration) time.Duration
 }
 
-type option func(c *http.Client) error
+type option func(c *client) error
 
 // NewClient 新建http client
-func NewClient(scheme, host string, opts ...option) Client {
-	c := &http.Client{}
-	for _, opt := range opts {
-		opt(c)
+func NewClient(scheme, host string, opts ...option) (Client, error) {
+	c, err := newClient(scheme, host, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.roundTrip = chain(c.middlewares, c.httpClient.Do)
+	return c, nil
+}
+
+// newClient 新建client并应用选项，roundTrip由调用方设置
+func newClient(scheme, host string, opts ...option) (*client, error) {
+	// 连接池默认配置与http.DefaultTransport一致
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
 	}
-	return &client{
-		httpClient: c,
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	c := &client{
+		httpClient: &http.Client{Transport: transport},
+		transport:  transport,
+		dialer:     dialer,
 		scheme:     scheme,
 		host:       host,
+		codec:      encoding.GetCodec(CodecJSON),
+		balancer:   BalancerP2C,
+		clock:      handle.NewRealClock(),
+		jitter:     defaultJitter,
 	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("apply option fail, err: %v", err)
+		}
+	}
+	return c, nil
 }
 
 // OptionWithTimeout 设置超时时间
 func OptionWithTimeout(timeout time.Duration) option {
-	return func(c *http.Client) error {
-		c.Timeout = timeout
+	return func(c *client) error {
+		c.httpClient.Timeout = timeout
 		return nil
 	}
 }
 
+// OptionWithCodec 设置请求体编码格式，name为已注册的codec名称，例如CodecForm
+func OptionWithCodec(name string) option {
+	return func(c *client) error {
+		codec, err := getCodec(name)
+		if err != nil {
+			return err
+		}
+		c.codec = codec
+		return nil
+	}
+}
+
+// OptionWithMaxResponseSize 设置响应体最大字节数，超过时返回ErrResponseTooLarge，避免异常响应撑爆内存
+func OptionWithMaxResponseSize(size int64) option {
+	return func(c *client) error {
+		c.maxResponseSize = size
+		return nil
+	}
+}
+
+// callOptions 单次请求的配置
+type callOptions struct {
+	header  http.Header
+	query   url.Values
+	timeout time.Duration
+	codec   string
+	// 以下仅Download使用
+	rangeStart     int64
+	checksum       hash.Hash
+	checksumExpect string
+	response       *Response // 非nil时写入响应元信息
+	route          string    // 路径模板，用于链路追踪和监控
+}
+
+// CallOption 单次请求选项
+type CallOption func(o *callOptions)
+
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{header: make(http.Header), query: make(url.Values)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// context 根据单次请求超时时间生成ctx
+func (o *callOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// CallWithHeader 设置请求头，多次调用同一个key会追加
+func CallWithHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// CallWithQuery 追加查询参数
+func CallWithQuery(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.query.Add(key, value)
+	}
+}
+
+// CallWithTimeout 设置单次请求超时时间，与client超时时间同时生效
+func CallWithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// CallWithCodec 设置单次请求的请求体编码格式，name为已注册的codec名称
+func CallWithCodec(name string) CallOption {
+	return func(o *callOptions) {
+		o.codec = name
+	}
+}
+
+// Get 发送GET请求
+func (c *client) Get(ctx context.Context, path string, query url.Values, rsp interface{}, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodGet, path, query, nil, rsp, opts...)
+}
+
 // Post 发送POST请求
-func (c *client) Post(ctx context.Context, path string, req, rsp interface{}) error {
+func (c *client) Post(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodPost, path, nil, req, rsp, opts...)
+}
+
+// Put 发送PUT请求
+func (c *client) Put(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodPut, path, nil, req, rsp, opts...)
+}
+
+// Patch 发送PATCH请求
+func (c *client) Patch(ctx context.Context, path string, req, rsp interface{}, opts ...CallOption) error {
+	return c.Do(ctx, http.MethodPatch, path, nil, req, rsp, opts...)
+}
+
+// Delete 发送DELETE请求
+func (c *client) Delete(ctx context.Context, path string, query url.Values, rsp interface{},
+	opts ...CallOption) error {
+	return c.Do(ctx, http.MethodDelete, path, query, nil, rsp, opts...)
+}
+
+// Do 发送任意方法的请求
+func (c *client) Do(ctx context.Context, method, path string, query url.Values, req, rsp interface{},
+	opts ...CallOption) error {
 	// 判断rsp必须是指针
-	if reflect.TypeOf(rsp).Kind() != reflect.Ptr {
+	if rsp != nil && reflect.TypeOf(rsp).Kind() != reflect.Ptr {
 		return fmt.Errorf("rsp must be a pointer")
 	}
-	breq, err := json.Marshal(req)
+	o := newCallOptions(opts)
+	ctx, cancel := o.context(ctx)
+	defer cancel()
+	httpRsp, codec, err := c.send(ctx, method, path, query, req, o)
 	if err != nil {
-		return fmt.Errorf("req is not json struct fail, err: %v", err)
+		return err
 	}
-	url := fmt.Sprintf("%s://%s%s", c.scheme, c.host, path)
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(breq))
+	defer httpRsp.Body.Close()
+	return c.decode(httpRsp, codec, rsp)
+}
+
+// decode 读取并解码响应体，优先按响应Content-Type选择codec，无法识别时使用codec
+func (c *client) decode(httpRsp *http.Response, codec Codec, rsp interface{}) error {
+	bodyRsp, err := c.readBody(httpRsp)
 	if err != nil {
-		return fmt.Errorf("new request with context fail, err: %v", err)
+		return err
+	}
+	if rsp == nil || len(bodyRsp) == 0 {
+		return nil
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpRsp, err := c.httpClient.Do(httpReq)
+	if rspCodec := codecForContentType(httpRsp.Header.Get("Content-Type")); rspCodec != nil {
+		codec = rspCodec
+	}
+	if err := codec.Unmarshal(bodyRsp, rsp); err != nil {
+		return fmt.Errorf("unmarshal by %s fail, err: %v", codec.Name(), err)
+	}
+	return nil
+}
+
+// send 编码请求体并发送请求，返回2xx响应和请求使用的codec，非2xx时返回StatusError
+func (c *client) send(ctx context.Context, method, path string, query url.Values, req interface{},
+	o *callOptions) (*http.Response, Codec, error) {
+	codec := c.codec
+	if o.codec != "" {
+		var err error
+		if codec, err = getCodec(o.codec); err != nil {
+			return nil, nil, err
+		}
+	}
+	var (
+		body io.Reader
+		ct   string
+	)
+	if req != nil {
+		breq, err := codec.Marshal(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("marshal req by %s fail, err: %v", codec.Name(), err)
+		}
+		if c.compression != "" && len(breq) >= c.compressMinSize {
+			if breq, err = compress(c.compression, breq); err != nil {
+				return nil, nil, fmt.Errorf("compress req by %s fail, err: %v", c.compression, err)
+			}
+			o.header.Set("Content-Encoding", c.compression)
+		}
+		body = bytes.NewReader(breq)
+		ct = contentType(codec)
+	}
+	httpRsp, err := c.sendBody(ctx, method, path, query, body, ct, o)
+	return httpRsp, codec, err
+}
+
+// sendBody 发送已编码的请求体，contentType为空时不设置Content-Type
+func (c *client) sendBody(ctx context.Context, method, path string, query url.Values, body io.Reader,
+	contentType string, o *callOptions) (*http.Response, error) {
+	u, err := c.buildURL(path, query, o.query)
 	if err != nil {
-		return fmt.Errorf("do http req fail, err: %v", err)
+		return nil, err
 	}
-	defer httpRsp.Body.Close()
+	if o.route != "" {
+		ctx = context.WithValue(ctx, routeKey{}, o.route)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request with context fail, err: %v", err)
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range o.header {
+		httpReq.Header[k] = v
+	}
+	httpRsp, err := c.roundTrip(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("do http req fail, err: %w", err)
+	}
+	if o.response != nil {
+		o.response.fill(httpRsp)
+	}
+	if httpRsp.StatusCode < 200 || httpRsp.StatusCode >= 300 {
+		bodyRsp, _ := ioutil.ReadAll(io.LimitReader(httpRsp.Body, int64(maxErrorBodyLen)))
+		drainBody(httpRsp.Body)
+		return nil, newStatusError(httpRsp, bodyRsp)
+	}
+	return httpRsp, nil
+}
 
-	bodyRsp, err := ioutil.ReadAll(httpRsp.Body)
+// drainBody 读完剩余的少量响应体后关闭，使连接可以复用，剩余过多时直接关闭连接
+func drainBody(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainBodyLen))
+	body.Close()
+}
+
+// readBody 读取响应体，超过maxResponseSize时返回ErrResponseTooLarge
+func (c *client) readBody(rsp *http.Response) ([]byte, error) {
+	if c.maxResponseSize <= 0 {
+		body, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read rsp fail, err: %v", err)
+		}
+		return body, nil
+	}
+	if rsp.ContentLength > c.maxResponseSize {
+		return nil, fmt.Errorf("%w, content length %d exceeds limit %d",
+			ErrResponseTooLarge, rsp.ContentLength, c.maxResponseSize)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(rsp.Body, c.maxResponseSize+1))
 	if err != nil {
-		return fmt.Errorf("read rsp fail, err: %v", err)
+		return nil, fmt.Errorf("read rsp fail, err: %v", err)
 	}
-	if err := json.Unmarshal(bodyRsp, rsp); err != nil {
-		return fmt.Errorf("unmarshal fail, err: %v", err)
+	if int64(len(body)) > c.maxResponseSize {
+		return nil, fmt.Errorf("%w, limit %d", ErrResponseTooLarge, c.maxResponseSize)
 	}
-	return nil
+	return body, nil
+}
+
+// buildURL 拼接请求地址，path中自带的查询参数与query、extra合并
+func (c *client) buildURL(path string, query, extra url.Values) (string, error) {
+	u, err := url.Parse(fmt.Sprintf("%s://%s%s", c.scheme, c.host, path))
+	if err != nil {
+		return "", fmt.Errorf("parse url fail, err: %v", err)
+	}
+	if len(query) == 0 && len(extra) == 0 {
+		return u.String(), nil
+	}
+	q := u.Query()
+	for _, values := range []url.Values{query, extra} {
+		for k, vs := range values {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
 }
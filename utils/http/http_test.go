@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestErrorBodyConnReuse(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(strings.Repeat("a", 32*maxErrorBodyLen))) // 超过StatusError保留的长度
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	c, err := NewClient("http", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		var se *StatusError
+		err := c.Get(context.Background(), "/", nil, nil)
+		if !errors.As(err, &se) || se.StatusCode != http.StatusBadRequest || len(se.Body) != maxErrorBodyLen {
+			t.Fatalf("err = %v, want 400 StatusError with truncated body", err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("conns = %d, want 1", n)
+	}
+}
@@ -0,0 +1,216 @@
+// Package httpmock http client测试辅助，支持录制回放真实请求和可编程的模拟服务
+package httpmock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode 录制回放模式
+type Mode int
+
+const (
+	ModeReplay Mode = iota // 只从golden文件回放，文件不存在时返回错误
+	ModeRecord             // 总是请求真实服务并覆盖golden文件
+	ModeAuto               // golden文件存在时回放，否则录制
+)
+
+// Recorder 录制回放RoundTripper，通过http.OptionWithTransport注入client，
+// 每个请求按方法、地址、请求体生成一个golden文件
+type Recorder struct {
+	dir  string
+	mode Mode
+	real http.RoundTripper
+}
+
+// NewRecorder 新建录制回放RoundTripper，dir为golden文件目录，real为录制时使用的真实RoundTripper，nil时使用http.DefaultTransport
+func NewRecorder(dir string, mode Mode, real http.RoundTripper) *Recorder {
+	if real == nil {
+		real = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, mode: mode, real: real}
+}
+
+// Interaction golden文件内容，一次请求和响应
+type Interaction struct {
+	Request  *Message `json:"request"`
+	Response *Message `json:"response"`
+}
+
+// Message 请求或响应
+type Message struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Base64     bool        `json:"base64,omitempty"` // Body不是合法utf8时以base64保存
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	reqHeader, normBody := normalizeMultipart(req.Header, reqBody)
+	file := filepath.Join(r.dir, goldenName(req, normBody))
+	if r.mode != ModeRecord {
+		it, err := load(file)
+		if err == nil {
+			return it.Response.toResponse(req)
+		}
+		if r.mode == ModeReplay || !os.IsNotExist(err) {
+			return nil, fmt.Errorf("replay %s %s fail, err: %v", req.Method, req.URL, err)
+		}
+	}
+	rsp, err := r.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read rsp fail, err: %v", err)
+	}
+	it := &Interaction{
+		Request:  newMessage(normBody, redact(reqHeader, redactHeaders)),
+		Response: newMessage(rspBody, redact(rsp.Header, redactRspHeaders)),
+	}
+	it.Request.Method, it.Request.URL = req.Method, req.URL.String()
+	it.Response.StatusCode = rsp.StatusCode
+	if err := save(file, it); err != nil {
+		return nil, err
+	}
+	return it.Response.toResponse(req)
+}
+
+var (
+	// redactHeaders 录制时不保存的敏感请求头
+	redactHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Auth-Signature"}
+	// redactRspHeaders 录制时不保存的敏感响应头
+	redactRspHeaders = []string{"Set-Cookie", "Set-Cookie2", "Proxy-Authenticate"}
+)
+
+// multipartBoundary multipart请求体中随机boundary替换后的固定值
+const multipartBoundary = "httpmock-boundary"
+
+func redact(header http.Header, keys []string) http.Header {
+	header = header.Clone()
+	for _, k := range keys {
+		header.Del(k)
+	}
+	return header
+}
+
+// normalizeMultipart multipart每次请求的boundary随机生成，替换为固定值后才能稳定匹配golden文件
+func normalizeMultipart(header http.Header, body []byte) (http.Header, []byte) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return header, body
+	}
+	body = bytes.ReplaceAll(body, []byte(params["boundary"]), []byte(multipartBoundary))
+	params["boundary"] = multipartBoundary
+	header = header.Clone()
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return header, body
+}
+
+// readBody 读取请求体并重置，保证真实请求仍能发送
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read req body fail, err: %v", err)
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// goldenName 文件名由方法、路径和请求摘要组成，便于人工查找；摘要不包含host，测试服务端口变化不影响回放，
+// body为替换multipart boundary后的请求体
+func goldenName(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	h.Write(body)
+	path := strings.Trim(strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(req.URL.Path), "_")
+	if len(path) > 64 {
+		path = path[:64]
+	}
+	return fmt.Sprintf("%s_%s_%s.json", req.Method, path, hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+func newMessage(body []byte, header http.Header) *Message {
+	m := &Message{Header: header}
+	if utf8.Valid(body) {
+		m.Body = string(body)
+	} else {
+		m.Body, m.Base64 = base64.StdEncoding.EncodeToString(body), true
+	}
+	return m
+}
+
+func (m *Message) body() ([]byte, error) {
+	if m.Base64 {
+		return base64.StdEncoding.DecodeString(m.Body)
+	}
+	return []byte(m.Body), nil
+}
+
+func (m *Message) toResponse(req *http.Request) (*http.Response, error) {
+	body, err := m.body()
+	if err != nil {
+		return nil, fmt.Errorf("decode golden body fail, err: %v", err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", m.StatusCode, http.StatusText(m.StatusCode)),
+		StatusCode:    m.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        m.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func load(file string) (*Interaction, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	it := &Interaction{}
+	if err := json.Unmarshal(data, it); err != nil {
+		return nil, fmt.Errorf("unmarshal golden file %s fail, err: %v", file, err)
+	}
+	return it, nil
+}
+
+func save(file string, it *Interaction) error {
+	data, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal golden file fail, err: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("mkdir %s fail, err: %v", filepath.Dir(file), err)
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("write golden file %s fail, err: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package httpmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sync"
+	"testing"
+)
+
+// Request 模拟服务收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server 可编程的模拟服务，按方法和路径匹配路由并返回预设响应，记录收到的请求用于断言
+type Server struct {
+	*httptest.Server
+	t        testing.TB
+	lock     sync.Mutex
+	routes   []*Route
+	requests []*Request
+}
+
+// NewServer 新建并启动模拟服务，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Host 服务地址，用于http.NewClient("http", s.Host())
+func (s *Server) Host() string {
+	u, _ := url.Parse(s.URL)
+	return u.Host
+}
+
+// On 注册路由，pattern支持path.Match通配符，例如/users/*；先注册的路由优先匹配
+func (s *Server) On(method, pattern string) *Route {
+	r := &Route{method: method, pattern: pattern, status: http.StatusOK, header: make(http.Header), times: -1}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.routes = append(s.routes, r)
+	return r
+}
+
+// Requests 返回收到的所有请求
+func (s *Server) Requests() []*Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// AssertCalled 断言method、path的请求收到了times次
+func (s *Server) AssertCalled(method, path string, times int) {
+	s.t.Helper()
+	n := 0
+	for _, req := range s.Requests() {
+		if req.Method == method && req.Path == path {
+			n++
+		}
+	}
+	if n != times {
+		s.t.Errorf("%s %s called %d times, expect %d", method, path, n, times)
+	}
+}
+
+// AssertExpectations 断言通过Times设置了调用次数的路由均被调用了对应次数
+func (s *Server) AssertExpectations() {
+	s.t.Helper()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, r := range s.routes {
+		if r.times >= 0 && r.calls != r.times {
+			s.t.Errorf("route %s %s called %d times, expect %d", r.method, r.pattern, r.calls, r.times)
+		}
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r := &Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+	s.lock.Lock()
+	s.requests = append(s.requests, r)
+	route := s.match(r)
+	if route != nil {
+		route.calls++
+	}
+	s.lock.Unlock()
+	if route == nil {
+		s.t.Errorf("unexpected request %s %s", r.Method, r.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	for _, check := range route.checks {
+		if err := check(r); err != nil {
+			s.t.Errorf("%s %s assert fail, err: %v", r.Method, r.Path, err)
+		}
+	}
+	if route.handler != nil {
+		route.handler(w, req)
+		return
+	}
+	for k, v := range route.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(route.status)
+	w.Write(route.body)
+}
+
+// match 调用方需持有锁，超过Times次数的路由不再匹配
+func (s *Server) match(r *Request) *Route {
+	for _, route := range s.routes {
+		if route.method != r.Method {
+			continue
+		}
+		if ok, _ := path.Match(route.pattern, r.Path); !ok {
+			continue
+		}
+		if route.times >= 0 && route.calls >= route.times {
+			continue
+		}
+		if !route.matchQuery(r.Query) {
+			continue
+		}
+		return route
+	}
+	return nil
+}
+
+// Route 模拟服务的路由
+type Route struct {
+	method  string
+	pattern string
+	query   url.Values
+	status  int
+	header  http.Header
+	body    []byte
+	handler http.HandlerFunc
+	checks  []func(r *Request) error
+	times   int // 期望调用次数，-1表示不限制
+	calls   int
+}
+
+// WithQuery 仅匹配携带指定查询参数的请求
+func (r *Route) WithQuery(key, value string) *Route {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+// Reply 设置响应状态码和响应体，body为[]byte、string时原样返回，其它类型编码为json
+func (r *Route) Reply(status int, body interface{}) *Route {
+	r.status = status
+	switch b := body.(type) {
+	case nil:
+		r.body = nil
+	case []byte:
+		r.body = b
+	case string:
+		r.body = []byte(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic(fmt.Sprintf("marshal reply body fail, err: %v", err))
+		}
+		r.body = data
+		r.header.Set("Content-Type", "application/json")
+	}
+	return r
+}
+
+// ReplyHeader 设置响应头
+func (r *Route) ReplyHeader(key, value string) *Route {
+	r.header.Set(key, value)
+	return r
+}
+
+// Handle 使用自定义handler生成响应，设置后Reply不再生效
+func (r *Route) Handle(handler http.HandlerFunc) *Route {
+	r.handler = handler
+	return r
+}
+
+// Times 设置期望调用次数，超过次数后该路由不再匹配，配合AssertExpectations使用
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+// ExpectHeader 断言请求携带指定请求头
+func (r *Route) ExpectHeader(key, value string) *Route {
+	return r.Expect(func(req *Request) error {
+		if got := req.Header.Get(key); got != value {
+			return fmt.Errorf("header %s is %q, expect %q", key, got, value)
+		}
+		return nil
+	})
+}
+
+// ExpectJSON 断言请求体与expect编码后的json语义相等
+func (r *Route) ExpectJSON(expect interface{}) *Route {
+	want, err := json.Marshal(expect)
+	if err != nil {
+		panic(fmt.Sprintf("marshal expect body fail, err: %v", err))
+	}
+	return r.Expect(func(req *Request) error {
+		var got, exp interface{}
+		if err := json.Unmarshal(req.Body, &got); err != nil {
+			return fmt.Errorf("body is not json, err: %v", err)
+		}
+		json.Unmarshal(want, &exp)
+		gotData, _ := json.Marshal(got)
+		expData, _ := json.Marshal(exp)
+		if string(gotData) != string(expData) {
+			return fmt.Errorf("body is %s, expect %s", gotData, expData)
+		}
+		return nil
+	})
+}
+
+// Expect 添加自定义请求断言，返回错误时测试失败
+func (r *Route) Expect(check func(req *Request) error) *Route {
+	r.checks = append(r.checks, check)
+	return r
+}
+
+func (r *Route) matchQuery(query url.Values) bool {
+	for k, vs := range r.query {
+		for _, v := range vs {
+			if !contains(query[k], v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, item := range values {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"net/http"
+)
+
+// RoundTripFunc 发送http请求并返回响应
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware 请求拦截器，可在next前后修改请求、观察响应，用于鉴权、日志、监控、链路追踪等
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// OptionWithMiddleware 添加拦截器，多个拦截器按添加顺序由外向内执行
+func OptionWithMiddleware(mws ...Middleware) option {
+	return func(c *client) error {
+		c.middlewares = append(c.middlewares, mws...)
+		return nil
+	}
+}
+
+// chain 将拦截器串联到next之上，第一个拦截器在最外层
+func chain(mws []Middleware, next RoundTripFunc) RoundTripFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		next = mws[i](next)
+	}
+	return next
+}
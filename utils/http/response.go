@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+)
+
+// Response 响应元信息，通过CallWithResponse获取状态码和响应头，例如分页游标、限流剩余次数、ETag
+type Response struct {
+	StatusCode    int         // 响应状态码
+	Status        string      // 响应状态描述，例如"200 OK"
+	Header        http.Header // 响应头
+	ContentLength int64       // 响应体长度，未知为-1
+}
+
+// CallWithResponse 请求完成后将响应元信息写入meta，非2xx响应同样会写入
+func CallWithResponse(meta *Response) CallOption {
+	return func(o *callOptions) {
+		o.response = meta
+	}
+}
+
+// fill 用http响应填充元信息
+func (r *Response) fill(rsp *http.Response) {
+	r.StatusCode = rsp.StatusCode
+	r.Status = rsp.Status
+	r.Header = rsp.Header
+	r.ContentLength = rsp.ContentLength
+}
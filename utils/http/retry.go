@@ -0,0 +1,191 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jensenguo/project-go/utils/handle"
+)
+
+// HeaderIdempotencyKey 幂等键请求头，携带该请求头的POST、PATCH请求也会重试
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+var (
+	maxRetryBackoff = 10 * time.Second // 指数退避的最大等待时间
+	maxRetryAfter   = time.Minute      // Retry-After超过该值时不再重试
+)
+
+// OptionWithRetry 开启失败重试，maxAttempts为最大请求次数（含首次），backoff为首次重试等待时间，之后指数增长
+// 仅在连接错误以及IsRetryableStatus状态码时重试，响应带有Retry-After时优先使用其作为等待时间；
+// 默认只重试幂等方法，POST、PATCH需通过CallWithIdempotencyKey携带幂等键才会重试
+func OptionWithRetry(maxAttempts int, backoff time.Duration) option {
+	return func(c *client) error {
+		c.middlewares = append(c.middlewares, c.retryMiddleware(maxAttempts, backoff))
+		return nil
+	}
+}
+
+// OptionWithClock 设置重试及服务发现监听失败后等待使用的时钟，测试时传入handle.FakeClock可不消耗真实时间验证等待间隔
+func OptionWithClock(clock handle.Clock) option {
+	return func(c *client) error {
+		c.clock = clock
+		return nil
+	}
+}
+
+// OptionWithRetryJitter 设置重试等待时间的抖动函数，入参为指数退避时长，默认在后一半区间内随机，
+// 测试时可传入不抖动的函数使重试间隔确定
+func OptionWithRetryJitter(jitter func(d time.Duration) time.Duration) option {
+	return func(c *client) error {
+		c.jitter = jitter
+		return nil
+	}
+}
+
+// defaultJitter 在[d/2, d]区间内随机抖动
+func defaultJitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// CallWithIdempotencyKey 设置幂等键，允许非幂等方法的请求重试
+func CallWithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) {
+		o.header.Set(HeaderIdempotencyKey, key)
+	}
+}
+
+// retryMiddleware 时钟与抖动函数在请求时读取，不依赖选项顺序
+func (c *client) retryMiddleware(maxAttempts int, backoff time.Duration) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if maxAttempts <= 1 || !canRetry(req) {
+				return next(req)
+			}
+			ctx := req.Context()
+			for attempt := 1; ; attempt++ {
+				r := req
+				if attempt > 1 && req.Body != nil { // 重试时重新获取请求体
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					r = req.Clone(ctx)
+					r.Body = body
+				}
+				rsp, err := next(r)
+				if attempt >= maxAttempts {
+					return rsp, err
+				}
+				wait, ok := c.retryWait(rsp, err, attempt, backoff)
+				if !ok || ctx.Err() != nil { // 无需重试，或调用方已取消、超时
+					return rsp, err
+				}
+				if rsp != nil { // 读完并关闭响应体以复用连接
+					drainBody(rsp.Body)
+				}
+				timer := c.clock.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C():
+				}
+			}
+		}
+	}
+}
+
+// canRetry 幂等方法或携带幂等键，且请求体可以重放
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// retryWait 判断是否需要重试，并返回重试前的等待时间
+func (c *client) retryWait(rsp *http.Response, err error, attempt int, backoff time.Duration) (time.Duration, bool) {
+	if err != nil {
+		if !isConnError(err) {
+			return 0, false
+		}
+		return c.jitter(backoffDuration(attempt, backoff)), true
+	}
+	if !IsRetryableStatus(rsp.StatusCode) {
+		return 0, false
+	}
+	if wait, ok := parseRetryAfter(rsp.Header.Get("Retry-After"), c.clock.Now()); ok {
+		if wait > maxRetryAfter {
+			return 0, false
+		}
+		return wait, true
+	}
+	return c.jitter(backoffDuration(attempt, backoff)), true
+}
+
+// isConnError 是否为连接建立、读写失败或超时等网络错误，认证、TLS证书校验、解码等错误重试也不会成功
+func isConnError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) { // url.Error实现了net.Error，需取出底层错误判断
+		err = urlErr.Err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoffDuration 指数退避，最大maxRetryBackoff
+func backoffDuration(attempt int, backoff time.Duration) time.Duration {
+	d := backoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// parseRetryAfter 解析Retry-After，支持秒数和HTTP时间两种格式，now为当前时间
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0, false
+		}
+		return time.Duration(sec) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	wait := t.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jensenguo/project-go/utils/handle"
+)
+
+// recordClock 记录每次重试等待的时长，并立即把时间推进到定时器到期
+type recordClock struct {
+	*handle.FakeClock
+	waits []time.Duration
+}
+
+func (c *recordClock) NewTimer(d time.Duration) handle.Timer {
+	c.waits = append(c.waits, d)
+	t := c.FakeClock.NewTimer(d)
+	c.Advance(d)
+	return t
+}
+
+// retryResult 模拟一次请求的结果，err不为nil时返回错误，否则返回code和header组成的响应
+type retryResult struct {
+	code   int
+	header http.Header
+	err    error
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetry(t *testing.T) {
+	now := time.Date(2023, 3, 12, 4, 34, 3, 0, time.UTC)
+	unavailable := retryResult{code: http.StatusServiceUnavailable}
+	tests := []struct {
+		name      string
+		method    string
+		key       string // 幂等键
+		results   []retryResult
+		wantCalls int
+		wantWaits []time.Duration
+		wantCode  int
+		wantErr   error
+	}{
+		{
+			name: "backoff", method: http.MethodGet,
+			results: []retryResult{{err: syscall.ECONNRESET}, {err: syscall.ECONNREFUSED}, unavailable,
+				{code: http.StatusOK}},
+			wantCalls: 4, wantWaits: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond,
+				400 * time.Millisecond}, wantCode: http.StatusOK,
+		},
+		{
+			name: "exceed max attempts", method: http.MethodGet,
+			results:   []retryResult{unavailable, unavailable, unavailable, unavailable, unavailable},
+			wantCalls: 4, wantWaits: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond,
+				400 * time.Millisecond}, wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "retry after seconds", method: http.MethodGet,
+			results: []retryResult{{code: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"3"}}},
+				{code: http.StatusOK}},
+			wantCalls: 2, wantWaits: []time.Duration{3 * time.Second}, wantCode: http.StatusOK,
+		},
+		{
+			name: "retry after http date", method: http.MethodGet,
+			results: []retryResult{{code: http.StatusServiceUnavailable,
+				header: http.Header{"Retry-After": {now.Add(5 * time.Second).Format(http.TimeFormat)}}},
+				{code: http.StatusOK}},
+			wantCalls: 2, wantWaits: []time.Duration{5 * time.Second}, wantCode: http.StatusOK,
+		},
+		{
+			name: "retry after too long", method: http.MethodGet,
+			results:   []retryResult{{code: http.StatusServiceUnavailable, header: http.Header{"Retry-After": {"120"}}}},
+			wantCalls: 1, wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "not retryable status", method: http.MethodGet,
+			results:   []retryResult{{code: http.StatusBadRequest}},
+			wantCalls: 1, wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not conn error", method: http.MethodGet,
+			results:   []retryResult{{err: context.Canceled}},
+			wantCalls: 1, wantErr: context.Canceled,
+		},
+		{
+			name: "post without idempotency key", method: http.MethodPost,
+			results:   []retryResult{unavailable, {code: http.StatusOK}},
+			wantCalls: 1, wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "post with idempotency key", method: http.MethodPost, key: "k1",
+			results:   []retryResult{unavailable, {code: http.StatusOK}},
+			wantCalls: 2, wantWaits: []time.Duration{100 * time.Millisecond}, wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordClock{FakeClock: handle.NewFakeClock(now)}
+			c := &client{clock: clock, jitter: func(d time.Duration) time.Duration { return d }}
+			var bodies []string
+			next := func(req *http.Request) (*http.Response, error) {
+				if req.Body != nil {
+					b, _ := io.ReadAll(req.Body)
+					bodies = append(bodies, string(b))
+				}
+				r := tt.results[len(bodies)-1]
+				if r.err != nil {
+					return nil, r.err
+				}
+				return &http.Response{StatusCode: r.code, Header: r.header,
+					Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			req, _ := http.NewRequest(tt.method, "http://example.com/", bytes.NewReader([]byte("body")))
+			if tt.key != "" {
+				req.Header.Set(HeaderIdempotencyKey, tt.key)
+			}
+			rsp, err := c.retryMiddleware(4, 100*time.Millisecond)(next)(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && rsp.StatusCode != tt.wantCode {
+				t.Errorf("code = %d, want %d", rsp.StatusCode, tt.wantCode)
+			}
+			if len(bodies) != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", len(bodies), tt.wantCalls)
+			}
+			for i, b := range bodies {
+				if b != "body" {
+					t.Errorf("call %d body = %q, want replayed body", i+1, b)
+				}
+			}
+			if !reflect.DeepEqual(clock.waits, tt.wantWaits) {
+				t.Errorf("waits = %v, want %v", clock.waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, maxRetryBackoff,
+		maxRetryBackoff}
+	for i, w := range want {
+		if got := backoffDuration(i+1, time.Second); got != w {
+			t.Errorf("backoffDuration(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 3, 12, 4, 34, 3, 0, time.UTC)
+	tests := []struct {
+		in     string
+		want   time.Duration
+		wantOK bool
+	}{
+		{in: "", wantOK: false},
+		{in: "0", want: 0, wantOK: true},
+		{in: "30", want: 30 * time.Second, wantOK: true},
+		{in: "-1", wantOK: false},
+		{in: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, wantOK: true},
+		{in: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, wantOK: true},
+		{in: "Sun, 12 Mar 2023 04:34:13 GMT", want: 10 * time.Second, wantOK: true},
+		{in: "tomorrow", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.in, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestIsConnError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: io.EOF, want: true},
+		{err: io.ErrUnexpectedEOF, want: true},
+		{err: syscall.ECONNRESET, want: true},
+		{err: syscall.EPIPE, want: true},
+		{err: &url.Error{Op: "Get", URL: "http://example.com", Err: syscall.ECONNREFUSED}, want: true},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, want: true},
+		{err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("closed")}, want: true},
+		{err: &url.Error{Op: "Get", URL: "http://example.com", Err: timeoutError{}}, want: true},
+		{err: fmt.Errorf("do http req fail, err: %w", syscall.ECONNREFUSED), want: true},
+		{err: &url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("x509: unknown authority")}},
+		{err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("auth required")}},
+		{err: context.Canceled},
+		{err: errors.New("unmarshal fail")},
+	}
+	for _, tt := range tests {
+		if got := isConnError(tt.err); got != tt.want {
+			t.Errorf("isConnError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/jensenguo/project-go/utils/coroutine"
+)
+
+// HeaderRequestID 请求ID请求头
+const HeaderRequestID = "X-Request-ID"
+
+// panicBufLen Recovery记录panic调用栈的buffer大小
+const panicBufLen = 4096
+
+// defaultMaxRequestBodySize Server默认的请求体最大字节数
+const defaultMaxRequestBodySize = 4 << 20
+
+var (
+	ctxType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type requestIDKey struct{}
+
+// errorBody 错误响应体，client收到后可从StatusError.Body解析
+type errorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewStatusError 新建指定状态码的错误，handler返回该错误时按状态码和message响应
+func NewStatusError(code int, message string) *StatusError {
+	body, _ := json.Marshal(&errorBody{Code: code, Message: message})
+	return &StatusError{
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       body,
+		local:      true,
+	}
+}
+
+// hopHeaders 与连接、响应体长度及编码相关的响应头，写入错误响应时不能沿用
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding",
+	"Upgrade", "Content-Length", "Content-Encoding"}
+
+// JSONHandler 将func(ctx context.Context, req *Req) (*Rsp, error)适配为http.Handler，
+// GET、DELETE请求从查询参数解码req，其它方法按Content-Type解码请求体，默认json；
+// 响应按Accept编码，默认json；返回*StatusError时按其状态码响应，其它错误响应500；
+// 请求体超过Server设置的大小时响应413，单独使用时可用http.MaxBytesHandler限制请求体大小；
+// fn签名不符合要求时panic
+func JSONHandler(fn interface{}) http.Handler {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 2 || ft.NumOut() != 2 || ft.In(0) != ctxType ||
+		ft.In(1).Kind() != reflect.Ptr || ft.Out(1) != errorType {
+		panic(fmt.Sprintf("handler must be func(context.Context, *Req) (*Rsp, error), got %v", ft))
+	}
+	reqType := ft.In(1).Elem()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := reflect.New(reqType)
+		if err := decodeRequest(r, req.Interface()); err != nil {
+			code := http.StatusBadRequest
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			WriteError(w, NewStatusError(code, err.Error()))
+			return
+		}
+		out := fv.Call([]reflect.Value{reflect.ValueOf(r.Context()), req})
+		if err, _ := out[1].Interface().(error); err != nil {
+			WriteError(w, err)
+			return
+		}
+		writeResponse(w, r, out[0].Interface())
+	})
+}
+
+// decodeRequest 解码请求参数
+func decodeRequest(r *http.Request, req interface{}) error {
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+		if r.URL.RawQuery == "" {
+			return nil
+		}
+		return encoding.GetCodec(CodecForm).Unmarshal([]byte(r.URL.RawQuery), req)
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("read body fail, err: %w", err)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	codec := codecForContentType(r.Header.Get("Content-Type"))
+	if codec == nil {
+		codec = encoding.GetCodec(CodecJSON)
+	}
+	if err := codec.Unmarshal(body, req); err != nil {
+		return fmt.Errorf("unmarshal by %s fail, err: %v", codec.Name(), err)
+	}
+	return nil
+}
+
+// writeResponse 按Accept编码响应
+func writeResponse(w http.ResponseWriter, r *http.Request, rsp interface{}) {
+	codec := codecForContentType(r.Header.Get("Accept"))
+	if codec == nil {
+		codec = encoding.GetCodec(CodecJSON)
+	}
+	body, err := codec.Marshal(rsp)
+	if err != nil {
+		WriteError(w, fmt.Errorf("marshal rsp by %s fail, err: %v", codec.Name(), err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType(codec))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// WriteError 写入错误响应，NewStatusError创建的错误按其状态码、响应头、响应体写入；
+// client调用上游返回的*StatusError不透传上游响应头和响应体，上游4xx沿用状态码，其它映射为502；其它错误响应500
+func WriteError(w http.ResponseWriter, err error) {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		log.Errorf("http handler fail, err: %v", err)
+		se = NewStatusError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	} else if !se.local {
+		log.Errorf("http handler upstream fail, err: %v", err)
+		code := se.StatusCode
+		if code < http.StatusBadRequest || se.IsServerError() {
+			code = http.StatusBadGateway
+		}
+		se = NewStatusError(code, "upstream "+se.Status)
+	}
+	for k, v := range se.Header {
+		w.Header()[k] = v
+	}
+	for _, k := range hopHeaders {
+		w.Header().Del(k)
+	}
+	w.WriteHeader(se.StatusCode)
+	w.Write(se.Body)
+}
+
+// RequestID 请求ID中间件，优先使用请求头中的X-Request-ID，没有则生成，写入响应头和ctx
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext 获取RequestID中间件写入的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Recovery panic恢复中间件，记录调用栈并响应500；http.ErrAbortHandler按net/http约定继续panic以中断响应
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			buf := make([]byte, panicBufLen)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Errorf("[PANIC]%v\n%s\n", p, buf)
+			WriteError(w, NewStatusError(http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError)))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Server http服务，默认带有Recovery和RequestID中间件，限制请求体大小，支持优雅退出
+type Server struct {
+	srv             *http.Server
+	shutdownTimeout time.Duration
+	maxBodySize     int64
+	signals         []os.Signal
+}
+
+type serverOption func(s *Server)
+
+// NewServer 新建http服务
+func NewServer(addr string, handler http.Handler, opts ...serverOption) *Server {
+	s := &Server{
+		srv: &http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		shutdownTimeout: 30 * time.Second,
+		maxBodySize:     defaultMaxRequestBodySize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv.Handler = Recovery(RequestID(s.limitBody(handler)))
+	return s
+}
+
+// limitBody 限制请求体大小，超过时读取请求体返回*http.MaxBytesError
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	if s.maxBodySize <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServerOptionWithShutdownTimeout 设置优雅退出时等待请求处理完成的最长时间
+func ServerOptionWithShutdownTimeout(timeout time.Duration) serverOption {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
+
+// ServerOptionWithMaxBodySize 设置请求体最大字节数，默认4MB，不大于0时不限制
+func ServerOptionWithMaxBodySize(size int64) serverOption {
+	return func(s *Server) {
+		s.maxBodySize = size
+	}
+}
+
+// ServerOptionWithSignal Run收到指定信号时优雅退出，如syscall.SIGINT、syscall.SIGTERM；
+// 默认不监听信号，由调用方取消ctx
+func ServerOptionWithSignal(sigs ...os.Signal) serverOption {
+	return func(s *Server) {
+		s.signals = sigs
+	}
+}
+
+// ServerOptionWithReadTimeout 设置读取整个请求的超时时间
+func ServerOptionWithReadTimeout(timeout time.Duration) serverOption {
+	return func(s *Server) {
+		s.srv.ReadTimeout = timeout
+	}
+}
+
+// ServerOptionWithWriteTimeout 设置写响应的超时时间
+func ServerOptionWithWriteTimeout(timeout time.Duration) serverOption {
+	return func(s *Server) {
+		s.srv.WriteTimeout = timeout
+	}
+}
+
+// Run 启动服务并阻塞，ctx取消或收到ServerOptionWithSignal设置的信号时停止接收新请求，
+// 等待处理中的请求完成后返回；其它地方调用Shutdown时直接返回nil
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	coroutine.Go(func() {
+		errCh <- s.srv.ListenAndServe()
+	})
+	var sigCh chan os.Signal // 未设置信号时为nil，select永远不会选中
+	if len(s.signals) > 0 {
+		sigCh = make(chan os.Signal, 1)
+		signal.Notify(sigCh, s.signals...)
+		defer signal.Stop(sigCh)
+	}
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("listen and serve fail, err: %v", err)
+	case <-ctx.Done():
+	case sig := <-sigCh:
+		log.Infof("receive signal %v, shutdown server", sig)
+	}
+	return s.Shutdown()
+}
+
+// Shutdown 优雅退出，最多等待shutdownTimeout
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown server fail, err: %v", err)
+	}
+	return nil
+}
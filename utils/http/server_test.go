@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echoReq struct {
+	Name string `json:"name"`
+}
+
+func echo(ctx context.Context, req *echoReq) (*echoReq, error) {
+	return req, nil
+}
+
+func TestServerMaxBodySize(t *testing.T) {
+	s := NewServer(":0", JSONHandler(echo), ServerOptionWithMaxBodySize(16))
+	tests := []struct {
+		body string
+		code int
+	}{
+		{body: `{"name":"a"}`, code: http.StatusOK},
+		{body: `{"name":"` + strings.Repeat("a", 32) + `"}`, code: http.StatusRequestEntityTooLarge},
+		{body: `{"name":`, code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		s.srv.Handler.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("body %q code = %d, want %d", tt.body, w.Code, tt.code)
+		}
+	}
+}
+
+func TestServerRun(t *testing.T) {
+	s := NewServer("127.0.0.1:0", JSONHandler(echo))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Run(context.Background())
+	}()
+	// 等待ListenAndServe启动后由其它地方调用Shutdown，Run应正常返回
+	for i := 0; i < 100; i++ {
+		if err := s.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Errorf("Run err = %v", err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("Run not return after Shutdown")
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		panicVal  interface{}
+		wantPanic bool
+	}{
+		{name: "error", panicVal: "boom"},
+		{name: "abort", panicVal: http.ErrAbortHandler, wantPanic: true},
+	}
+	for _, tt := range tests {
+		h := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(tt.panicVal)
+		}))
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if p := recover(); (p != nil) != tt.wantPanic {
+					t.Errorf("%s: recover = %v, wantPanic %v", tt.name, p, tt.wantPanic)
+				}
+			}()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+		if !tt.wantPanic && w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: code = %d, want 500", tt.name, w.Code)
+		}
+	}
+}
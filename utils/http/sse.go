@@ -0,0 +1,209 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jensenguo/project-go/utils/coroutine"
+)
+
+var (
+	defaultSSERetry = 3 * time.Second        // 服务端未指定retry时的重连间隔
+	maxSSELineLen   = 1024 * 1024            // SSE单行最大长度
+	errSSEStop      = errors.New("sse stop") // 服务端返回204，要求客户端停止重连
+)
+
+// Event Server-Sent Events事件
+type Event struct {
+	ID    string        // 事件ID，未指定时沿用上一个事件的ID
+	Event string        // 事件类型，为空时表示message
+	Data  string        // 事件数据，多行data以\n拼接
+	Retry time.Duration // 服务端指定的重连间隔，未指定为0
+}
+
+// Subscribe 订阅SSE，每个事件调用一次handle，阻塞直到ctx取消、handle返回错误或服务端返回204；
+// 连接断开（包括client超时）、连接错误及可重试状态码时按服务端指定的retry间隔重连，并携带Last-Event-ID，
+// 地址错误、认证失败、不可重试状态码等永久错误直接返回
+func (c *client) Subscribe(ctx context.Context, path string, query url.Values, handle func(e *Event) error,
+	opts ...CallOption) error {
+	o := newCallOptions(opts)
+	ctx, cancel := o.context(ctx)
+	defer cancel()
+	o.header.Set("Accept", "text/event-stream")
+	o.header.Set("Cache-Control", "no-cache")
+	s := &sseStream{retry: defaultSSERetry}
+	for {
+		if s.lastID != "" {
+			o.header.Set("Last-Event-ID", s.lastID)
+		}
+		reconnect, err := c.subscribeOnce(ctx, path, query, o, s, handle)
+		if errors.Is(err, errSSEStop) {
+			return nil
+		}
+		if !reconnect {
+			return err
+		}
+		timer := time.NewTimer(s.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// SubscribeChan 以channel方式订阅SSE，订阅结束后关闭事件channel，并向错误channel发送结束原因（正常结束为nil）
+func SubscribeChan(ctx context.Context, c Client, path string, query url.Values,
+	opts ...CallOption) (<-chan *Event, <-chan error) {
+	events := make(chan *Event)
+	errs := make(chan error, 1)
+	coroutine.Go(func() {
+		defer close(errs)
+		defer close(events)
+		errs <- c.Subscribe(ctx, path, query, func(e *Event) error {
+			select {
+			case events <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+	})
+	return events, errs
+}
+
+// subscribeOnce 建立一次连接并读取事件直到连接断开，返回是否需要重连以及结束原因
+func (c *client) subscribeOnce(ctx context.Context, path string, query url.Values, o *callOptions, s *sseStream,
+	handle func(e *Event) error) (bool, error) {
+	httpRsp, err := c.sendBody(ctx, http.MethodGet, path, query, nil, "", o)
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) {
+			return se.Retryable(), err
+		}
+		return isConnError(err), err // 只有连接错误重连，其他错误重连也不会成功
+	}
+	defer httpRsp.Body.Close()
+	if httpRsp.StatusCode == http.StatusNoContent {
+		return false, errSSEStop
+	}
+	// 网关错误页等非事件流响应不能按事件解析，重连也不会成功
+	if ct := httpRsp.Header.Get("Content-Type"); !isEventStream(ct) {
+		return false, fmt.Errorf("unexpected sse content type %q", ct)
+	}
+	err = s.read(httpRsp.Body, handle)
+	if s.handleErr != nil || errors.Is(err, bufio.ErrTooLong) {
+		return false, err
+	}
+	return true, err // 连接正常关闭或读取中断
+}
+
+func isEventStream(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// sseStream SSE解析状态，跨重连保留
+type sseStream struct {
+	lastID    string
+	retry     time.Duration
+	handleErr error // handle返回的错误，不再重连
+}
+
+// read 按SSE规范逐行解析事件
+func (s *sseStream) read(r io.Reader, handle func(e *Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxSSELineLen)
+	scanner.Split(newSSELineSplit())
+	var (
+		data    strings.Builder
+		hasData bool
+		event   string
+		retry   time.Duration
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" { // 空行分发事件
+			if hasData {
+				e := &Event{ID: s.lastID, Event: event, Data: data.String(), Retry: retry}
+				if err := handle(e); err != nil {
+					s.handleErr = err
+					return err
+				}
+			}
+			data.Reset()
+			hasData, event, retry = false, "", 0
+			continue
+		}
+		if strings.HasPrefix(line, ":") { // 注释，常用于保活
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				s.retry = retry
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// newSSELineSplit 按SSE规范切分行，行尾可以是CRLF、LF或单独的CR；
+// CR位于已读数据末尾时立即返回该行，并在下次切分时跳过紧随的LF，避免等待后续数据才分发事件
+func newSSELineSplit() bufio.SplitFunc {
+	skipLF := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		start := 0
+		if skipLF && len(data) > 0 {
+			skipLF = false
+			if data[0] == '\n' {
+				start = 1
+			}
+		}
+		if i := bytes.IndexAny(data[start:], "\r\n"); i >= 0 {
+			i += start
+			if data[i] == '\r' {
+				if i+1 < len(data) {
+					if data[i+1] == '\n' {
+						return i + 2, data[start:i], nil
+					}
+				} else {
+					skipLF = true
+				}
+			}
+			return i + 1, data[start:i], nil
+		}
+		if atEOF && len(data) > start {
+			return len(data), data[start:], nil
+		}
+		return start, nil, nil
+	}
+}
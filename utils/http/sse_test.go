@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestSSERead(t *testing.T) {
+	want := []Event{
+		{ID: "1", Event: "add", Data: "a\nb"},
+		{ID: "1", Data: "c", Retry: 5 * time.Second},
+	}
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "lf", in: "id: 1\nevent: add\ndata: a\ndata: b\n\n: ping\ndata: c\nretry: 5000\n\n"},
+		{name: "crlf", in: "id: 1\r\nevent: add\r\ndata: a\r\ndata: b\r\n\r\n: ping\r\ndata: c\r\nretry: 5000\r\n\r\n"},
+		{name: "cr", in: "id: 1\revent: add\rdata: a\rdata: b\r\r: ping\rdata: c\rretry: 5000\r\r"},
+		{name: "mixed", in: "id: 1\revent: add\r\ndata: a\ndata: b\r\r\n: ping\rdata: c\nretry: 5000\r\n\n"},
+	}
+	for _, tt := range tests {
+		// 逐字节读取，覆盖CR与LF分属两次读取的情况
+		for _, oneByte := range []bool{false, true} {
+			var r io.Reader = strings.NewReader(tt.in)
+			if oneByte {
+				r = iotest.OneByteReader(r)
+			}
+			var got []Event
+			err := (&sseStream{}).read(r, func(e *Event) error {
+				got = append(got, *e)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("%s: err = %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s oneByte=%v: events = %+v, want %+v", tt.name, oneByte, got, want)
+			}
+		}
+	}
+}
+
+func TestSubscribeContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		ct      string
+		wantErr bool
+	}{
+		{name: "event stream", ct: "text/event-stream; charset=utf-8"},
+		{name: "html", ct: "text/html", wantErr: true},
+		{name: "json", ct: "application/json", wantErr: true},
+	}
+	for _, tt := range tests {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls > 1 { // 重连时要求客户端停止
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", tt.ct)
+			w.Write([]byte("data: hello\nretry: 1\n\n"))
+		}))
+		c, err := NewClient("http", strings.TrimPrefix(srv.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []string
+		err = c.Subscribe(context.Background(), "/events", nil, func(e *Event) error {
+			events = append(events, e.Data)
+			return nil
+		})
+		srv.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		wantEvents, wantCalls := []string{"hello"}, 2
+		if tt.wantErr {
+			wantEvents, wantCalls = nil, 1
+		}
+		if !reflect.DeepEqual(events, wantEvents) || calls != wantCalls {
+			t.Errorf("%s: events = %v, calls = %d, want %v, %d", tt.name, events, calls, wantEvents, wantCalls)
+		}
+	}
+}
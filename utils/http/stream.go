@@ -0,0 +1,79 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// maxNDJSONLineLen NDJSON单行最大长度
+var maxNDJSONLineLen = 16 * 1024 * 1024
+
+// Stream 发送请求并返回响应体流
+func (c *client) Stream(ctx context.Context, method, path string, query url.Values, req interface{},
+	opts ...CallOption) (io.ReadCloser, error) {
+	o := newCallOptions(opts)
+	ctx, cancel := o.context(ctx)
+	httpRsp, _, err := c.send(ctx, method, path, query, req, o)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &streamBody{ReadCloser: httpRsp.Body, cancel: cancel}, nil
+}
+
+// streamBody 关闭响应体时释放单次请求超时的ctx
+type streamBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *streamBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// ForEachNDJSON 逐行读取NDJSON（每行一个json），对每行调用handle，handle返回错误时停止
+func ForEachNDJSON(r io.Reader, handle func(line json.RawMessage) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineLen)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handle(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan ndjson fail, err: %v", err)
+	}
+	return nil
+}
+
+// ForEachJSONArray 增量解码顶层为数组的json，每个元素调用一次handle，
+// handle中通过dec.Decode解码当前元素，适用于无法一次加载到内存的大数组
+func ForEachJSONArray(r io.Reader, handle func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("read json token fail, err: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("json is not an array")
+	}
+	for dec.More() {
+		if err := handle(dec); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("read json token fail, err: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName otel tracer、meter名称
+const instrumentationName = "github.com/jensenguo/project-go/utils/http"
+
+type routeKey struct{}
+
+// CallWithRouteTemplate 设置路径模板，例如/users/{id}，用于span名称和监控维度；
+// 未设置时span名称只包含方法，如"HTTP GET"，且不记录路径维度，避免路径中的ID等参数导致维度过多
+func CallWithRouteTemplate(tmpl string) CallOption {
+	return func(o *callOptions) {
+		o.route = tmpl
+	}
+}
+
+// OptionWithTelemetry 开启OpenTelemetry链路追踪和监控，每次请求创建client span并注入W3C traceparent请求头，
+// 按host、路径模板、方法、状态码或错误类型error.type记录耗时直方图http.client.duration（毫秒）；tp、mp为nil时使用otel全局provider
+func OptionWithTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) option {
+	return func(c *client) error {
+		if tp == nil {
+			tp = otel.GetTracerProvider()
+		}
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+		duration, err := mp.Meter(instrumentationName).Float64Histogram("http.client.duration",
+			metric.WithUnit("ms"), metric.WithDescription("duration of outgoing http requests"))
+		if err != nil {
+			return fmt.Errorf("new histogram fail, err: %v", err)
+		}
+		t := &telemetry{tracer: tp.Tracer(instrumentationName), duration: duration}
+		c.middlewares = append(c.middlewares, t.middleware)
+		return nil
+	}
+}
+
+type telemetry struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+}
+
+func (t *telemetry) middleware(next RoundTripFunc) RoundTripFunc {
+	propagator := propagation.TraceContext{}
+	return func(req *http.Request) (*http.Response, error) {
+		attrs := []attribute.KeyValue{
+			semconv.HTTPMethod(req.Method),
+			semconv.NetPeerName(req.URL.Hostname()),
+		}
+		name := "HTTP " + req.Method
+		if route, _ := req.Context().Value(routeKey{}).(string); route != "" {
+			name += " " + route
+			attrs = append(attrs, attribute.String("http.route", route))
+		}
+		ctx, span := t.tracer.Start(req.Context(), name, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(append(attrs, semconv.HTTPURL(spanURL(req.URL)))...))
+		defer span.End()
+		req = req.Clone(ctx)
+		propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+		begin := time.Now()
+		rsp, err := next(req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			attrs = append(attrs, attribute.String("error.type", errorKind(err)))
+		} else {
+			span.SetAttributes(semconv.HTTPStatusCode(rsp.StatusCode))
+			if rsp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, rsp.Status)
+			}
+			attrs = append(attrs, semconv.HTTPStatusCode(rsp.StatusCode))
+		}
+		t.duration.Record(ctx, float64(time.Since(begin))/float64(time.Millisecond),
+			metric.WithAttributes(attrs...))
+		return rsp, err
+	}
+}
+
+// errorKind 请求失败的类型，作为监控维度，只有timeout、canceled、connection、other几种取值
+func errorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case isConnError(err):
+		return "connection"
+	}
+	return "other"
+}
+
+// spanURL 去掉查询参数、用户信息和fragment，避免token等敏感信息进入链路数据
+func spanURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.ForceQuery = false
+	clean.Fragment = ""
+	clean.RawFragment = ""
+	return clean.String()
+}
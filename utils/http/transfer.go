@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/jensenguo/project-go/utils/coroutine"
+)
+
+// maxDownloadResume 下载中断时最多续传次数
+var maxDownloadResume = 3
+
+// ErrChecksumMismatch 下载内容的校验和与期望值不一致
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrResourceChanged 续传时文件已变化，服务端按If-Range返回了新文件的完整内容，已写入w的部分与新文件不一致
+var ErrResourceChanged = errors.New("resource changed during download")
+
+// File multipart上传的文件
+type File struct {
+	FieldName   string    // 表单字段名
+	FileName    string    // 文件名
+	ContentType string    // 文件类型，为空时使用application/octet-stream
+	Reader      io.Reader // 文件内容，边读边发送，不会整体加载到内存
+}
+
+// CallWithRange 下载时从offset处开始续传，调用方已持有前offset字节
+func CallWithRange(offset int64) CallOption {
+	return func(o *callOptions) {
+		o.rangeStart = offset
+	}
+}
+
+// CallWithChecksum 下载完成后用h计算校验和并与expected（十六进制）比较，不一致返回ErrChecksumMismatch；
+// 配合CallWithRange续传时，调用方需要先把已持有的内容写入h
+func CallWithChecksum(h hash.Hash, expected string) CallOption {
+	return func(o *callOptions) {
+		o.checksum = h
+		o.checksumExpect = expected
+	}
+}
+
+// UploadMultipart 以multipart/form-data流式上传，请求体无法重放，不会自动重试
+func (c *client) UploadMultipart(ctx context.Context, path string, fields map[string]string, files []*File,
+	rsp interface{}, opts ...CallOption) error {
+	o := newCallOptions(opts)
+	ctx, cancel := o.context(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	defer pr.Close() // 请求提前结束时让写协程退出
+	mw := multipart.NewWriter(pw)
+	coroutine.Go(func() {
+		pw.CloseWithError(writeMultipart(mw, fields, files))
+	})
+	httpRsp, err := c.sendBody(ctx, http.MethodPost, path, nil, pr, mw.FormDataContentType(), o)
+	if err != nil {
+		return err
+	}
+	defer httpRsp.Body.Close()
+	return c.decode(httpRsp, c.codec, rsp)
+}
+
+// writeMultipart 按字段名顺序写入表单字段，再依次写入文件，保证相同参数的请求体一致
+func writeMultipart(mw *multipart.Writer, fields map[string]string, files []*File) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, fields[k]); err != nil {
+			return fmt.Errorf("write field %s fail, err: %v", k, err)
+		}
+	}
+	for _, f := range files {
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(f.FieldName), escapeQuotes(f.FileName)))
+		header.Set("Content-Type", ct)
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("create part %s fail, err: %v", f.FileName, err)
+		}
+		if _, err := io.Copy(part, f.Reader); err != nil {
+			return fmt.Errorf("copy file %s fail, err: %v", f.FileName, err)
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// Download 下载文件写入w，读取响应体中断时带Range和If-Range续传，续传时文件已变化返回ErrResourceChanged
+func (c *client) Download(ctx context.Context, path string, w io.Writer, opts ...CallOption) (int64, error) {
+	o := newCallOptions(opts)
+	ctx, cancel := o.context(ctx)
+	defer cancel()
+	dst := w
+	if o.checksum != nil {
+		dst = io.MultiWriter(w, o.checksum)
+	}
+	var (
+		written   int64
+		validator string // 首次响应的ETag或Last-Modified，保证续传的是同一个文件
+		lastErr   error
+	)
+	for i := 0; i <= maxDownloadResume; i++ {
+		offset := o.rangeStart + written
+		if offset > 0 {
+			o.header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		ifRange := offset > 0 && validator != ""
+		if ifRange {
+			o.header.Set("If-Range", validator)
+		}
+		httpRsp, err := c.sendBody(ctx, http.MethodGet, path, nil, nil, "", o)
+		if err != nil {
+			return written, err
+		}
+		if validator == "" {
+			validator = rangeValidator(httpRsp.Header)
+		}
+		var skip int64
+		if offset > 0 && httpRsp.StatusCode != http.StatusPartialContent {
+			if ifRange { // 带If-Range时返回完整内容说明文件已变化，不能与已写入的旧内容拼接
+				httpRsp.Body.Close()
+				return written, ErrResourceChanged
+			}
+			skip = offset // 服务端不支持Range返回完整内容，跳过已持有的部分
+		}
+		n, err := copySkip(dst, httpRsp.Body, skip)
+		httpRsp.Body.Close()
+		written += n
+		if err == nil {
+			return written, verifyChecksum(o)
+		}
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+		lastErr = err
+	}
+	return written, fmt.Errorf("download fail after %d resumes, err: %v", maxDownloadResume, lastErr)
+}
+
+// rangeValidator If-Range只能使用强ETag或Last-Modified
+func rangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// copySkip 丢弃src的前skip字节后复制到dst，返回写入dst的字节数
+func copySkip(dst io.Writer, src io.Reader, skip int64) (int64, error) {
+	if skip > 0 {
+		if _, err := io.CopyN(ioutil.Discard, src, skip); err != nil {
+			return 0, fmt.Errorf("skip downloaded bytes fail, err: %v", err)
+		}
+	}
+	return io.Copy(dst, src)
+}
+
+func verifyChecksum(o *callOptions) error {
+	if o.checksum == nil {
+		return nil
+	}
+	sum := hex.EncodeToString(o.checksum.Sum(nil))
+	if !strings.EqualFold(sum, o.checksumExpect) {
+		return fmt.Errorf("%w, expect %s, actual %s", ErrChecksumMismatch, o.checksumExpect, sum)
+	}
+	return nil
+}
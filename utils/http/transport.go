@@ -0,0 +1,101 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// OptionWithMaxIdleConns 设置所有host的最大空闲连接数
+func OptionWithMaxIdleConns(n int) option {
+	return func(c *client) error {
+		c.transport.MaxIdleConns = n
+		return nil
+	}
+}
+
+// OptionWithMaxIdleConnsPerHost 设置每个host的最大空闲连接数，标准库默认只有2，高并发时会频繁新建连接
+func OptionWithMaxIdleConnsPerHost(n int) option {
+	return func(c *client) error {
+		c.transport.MaxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// OptionWithMaxConnsPerHost 设置每个host的最大连接数，包括正在使用和空闲的连接，0表示不限制
+func OptionWithMaxConnsPerHost(n int) option {
+	return func(c *client) error {
+		c.transport.MaxConnsPerHost = n
+		return nil
+	}
+}
+
+// OptionWithIdleConnTimeout 设置空闲连接的最长保留时间
+func OptionWithIdleConnTimeout(timeout time.Duration) option {
+	return func(c *client) error {
+		c.transport.IdleConnTimeout = timeout
+		return nil
+	}
+}
+
+// OptionWithTLSConfig 设置TLS配置，例如自定义根证书、客户端证书
+func OptionWithTLSConfig(cfg *tls.Config) option {
+	return func(c *client) error {
+		c.transport.TLSClientConfig = cfg
+		return nil
+	}
+}
+
+// OptionWithHTTP2 设置是否启用HTTP/2，默认启用
+func OptionWithHTTP2(enable bool) option {
+	return func(c *client) error {
+		c.transport.ForceAttemptHTTP2 = enable
+		if !enable {
+			// TLSNextProto为非nil的空map时禁用HTTP/2
+			c.transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		}
+		return nil
+	}
+}
+
+// OptionWithProxy 设置代理地址，例如http://127.0.0.1:8080，空字符串表示不使用代理，默认读取环境变量
+func OptionWithProxy(proxy string) option {
+	return func(c *client) error {
+		if proxy == "" {
+			c.transport.Proxy = nil
+			return nil
+		}
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("parse proxy %s fail, err: %v", proxy, err)
+		}
+		c.transport.Proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// OptionWithDialTimeout 设置建立连接的超时时间
+func OptionWithDialTimeout(timeout time.Duration) option {
+	return func(c *client) error {
+		c.dialer.Timeout = timeout
+		return nil
+	}
+}
+
+// OptionWithKeepAlive 设置TCP keep-alive探测间隔，负数表示关闭
+func OptionWithKeepAlive(interval time.Duration) option {
+	return func(c *client) error {
+		c.dialer.KeepAlive = interval
+		return nil
+	}
+}
+
+// OptionWithTransport 设置自定义RoundTripper，例如httpmock.Recorder，设置后其它连接池选项不再生效
+func OptionWithTransport(rt http.RoundTripper) option {
+	return func(c *client) error {
+		c.httpClient.Transport = rt
+		return nil
+	}
+}
@@ -0,0 +1,302 @@
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// maxSubnets Subnets一次最多枚举的子网数量
+const maxSubnets = 1 << 16
+
+// ParseCIDR 解析CIDR，不带掩码的单个ip视为/32或/128，返回的网段已按NormalizePrefix规范化
+func ParseCIDR(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return NormalizePrefix(netip.PrefixFrom(addr.WithZone(""), addr.BitLen()))
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return NormalizePrefix(prefix)
+}
+
+// NormalizePrefix 规范化网段：IPv4映射的IPv6网段（如::ffff:10.0.0.0/104）转换为对应的IPv4网段（10.0.0.0/8），
+// 去掉zone并按掩码对齐；映射网段掩码小于96时不完全落在::ffff:0:0/96内，返回错误
+func NormalizePrefix(prefix netip.Prefix) (netip.Prefix, error) {
+	if !prefix.IsValid() {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix %s", prefix)
+	}
+	addr, bits := prefix.Addr().WithZone(""), prefix.Bits()
+	if addr.Is4In6() {
+		if bits < 96 {
+			return netip.Prefix{}, fmt.Errorf("ipv4-mapped prefix %s shorter than /96", prefix)
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	return netip.PrefixFrom(addr, bits).Masked(), nil
+}
+
+// CIDRContains 判断ip是否在cidr网段内
+func CIDRContains(cidr, ip string) (bool, error) {
+	prefix, err := ParseCIDR(cidr)
+	if err != nil {
+		return false, err
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, err
+	}
+	return prefix.Contains(addr.Unmap().WithZone("")), nil
+}
+
+// FirstAddr 网段的第一个地址
+func FirstAddr(prefix netip.Prefix) netip.Addr {
+	return prefix.Masked().Addr()
+}
+
+// LastAddr 网段的最后一个地址
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr()
+	if addr.Is4() {
+		b := addr.As4()
+		setHostBits(b[:], prefix.Bits())
+		return netip.AddrFrom4(b)
+	}
+	b := addr.As16()
+	setHostBits(b[:], prefix.Bits())
+	return netip.AddrFrom16(b).WithZone(addr.Zone())
+}
+
+// setHostBits 把前bits位之后的所有位置1
+func setHostBits(b []byte, bits int) {
+	for i := range b {
+		switch {
+		case bits >= 8:
+			bits -= 8
+		case bits > 0:
+			b[i] |= 0xFF >> bits
+			bits = 0
+		default:
+			b[i] = 0xFF
+		}
+	}
+}
+
+// RangeToCIDRs 把[start, end]地址区间拆分为最少数量的CIDR
+func RangeToCIDRs(start, end netip.Addr) ([]netip.Prefix, error) {
+	start, end = start.Unmap().WithZone(""), end.Unmap().WithZone("") // 网段不带zone，否则比较永远不相等
+	if !start.IsValid() || !end.IsValid() || start.Is4() != end.Is4() {
+		return nil, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+	if end.Less(start) {
+		return nil, fmt.Errorf("range start %s after end %s", start, end)
+	}
+	var prefixes []netip.Prefix
+	for {
+		// 从最大的网段开始尝试，找到以start开头且不超过end的网段
+		var prefix netip.Prefix
+		for bits := 0; bits <= start.BitLen(); bits++ {
+			prefix = netip.PrefixFrom(start, bits).Masked()
+			if prefix.Addr() == start && !end.Less(LastAddr(prefix)) {
+				break
+			}
+		}
+		prefixes = append(prefixes, prefix)
+		last := LastAddr(prefix)
+		if last == end {
+			return prefixes, nil
+		}
+		start = last.Next()
+	}
+}
+
+// Subnets 把网段按新的掩码长度bits拆分为子网，最多枚举65536个，IPv4映射网段按规范化后的IPv4网段计算bits
+func Subnets(prefix netip.Prefix, bits int) ([]netip.Prefix, error) {
+	prefix, err := NormalizePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if bits < prefix.Bits() || bits > prefix.Addr().BitLen() {
+		return nil, fmt.Errorf("invalid subnet bits %d for %s", bits, prefix)
+	}
+	if bits-prefix.Bits() > 16 {
+		return nil, fmt.Errorf("too many subnets of /%d in %s, max %d", bits, prefix, maxSubnets)
+	}
+	subnets := make([]netip.Prefix, 0, 1<<(bits-prefix.Bits()))
+	addr := prefix.Addr()
+	for len(subnets) < cap(subnets) {
+		subnet := netip.PrefixFrom(addr, bits)
+		subnets = append(subnets, subnet)
+		addr = LastAddr(subnet).Next()
+	}
+	return subnets, nil
+}
+
+// SplitPrefix 把网段平分为两个子网，单个地址的网段无法拆分返回false
+func SplitPrefix(prefix netip.Prefix) (netip.Prefix, netip.Prefix, bool) {
+	prefix, err := NormalizePrefix(prefix)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, false
+	}
+	subnets, err := Subnets(prefix, prefix.Bits()+1)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, false
+	}
+	return subnets[0], subnets[1], true
+}
+
+// MergePrefixes 合并重叠及相邻的网段，返回覆盖相同地址的最少网段，IPv4在前，IPv6在后；
+// 网段先按NormalizePrefix规范化，无法规范化的非法网段被忽略
+func MergePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	type addrRange struct {
+		first, last netip.Addr
+	}
+	ranges := make([]addrRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		prefix, err := NormalizePrefix(prefix)
+		if err != nil {
+			continue
+		}
+		ranges = append(ranges, addrRange{first: FirstAddr(prefix), last: LastAddr(prefix)})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Less(ranges[j].first)
+	})
+	var merged []addrRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].first.Is4() == r.first.Is4() {
+			cur := &merged[n-1]
+			// 重叠或者紧邻都可以合并
+			if !cur.last.Less(r.first) || cur.last.Next() == r.first {
+				if cur.last.Less(r.last) {
+					cur.last = r.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	var result []netip.Prefix
+	for _, r := range merged {
+		cidrs, _ := RangeToCIDRs(r.first, r.last)
+		result = append(result, cidrs...)
+	}
+	return result
+}
+
+// PrefixSet 基于二叉前缀树的网段集合，同时支持IPv4和IPv6，查询耗时只与地址位数相关
+// 非并发安全，构建完成后可以并发查询
+type PrefixSet struct {
+	v4, v6 prefixNode
+	size   int
+}
+
+type prefixNode struct {
+	children [2]*prefixNode
+	terminal bool // 从根节点到此的路径是集合中的一个网段
+}
+
+// NewPrefixSet 通过CIDR列表新建网段集合，不带掩码的ip视为单个地址
+func NewPrefixSet(cidrs ...string) (*PrefixSet, error) {
+	s := &PrefixSet{}
+	for _, cidr := range cidrs {
+		prefix, err := ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %s fail, err: %v", cidr, err)
+		}
+		if err := s.Add(prefix); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add 添加网段，网段按NormalizePrefix规范化，非法网段返回错误
+func (s *PrefixSet) Add(prefix netip.Prefix) error {
+	prefix, err := NormalizePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	b, root := s.root(prefix.Addr())
+	node := root
+	for i := 0; i < prefix.Bits(); i++ {
+		if node.terminal { // 已被更大的网段覆盖
+			return nil
+		}
+		bit := bitAt(b, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &prefixNode{}
+		}
+		node = node.children[bit]
+	}
+	if !node.terminal {
+		s.size += 1 - node.count()
+		node.terminal = true
+		node.children = [2]*prefixNode{} // 子网段已被覆盖，无需保留
+	}
+	return nil
+}
+
+// Contains 判断地址是否在集合的任一网段内
+func (s *PrefixSet) Contains(addr netip.Addr) bool {
+	_, ok := s.Lookup(addr)
+	return ok
+}
+
+// ContainsString 判断ip字符串是否在集合的任一网段内，非法ip返回false
+func (s *PrefixSet) ContainsString(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return s.Contains(addr)
+}
+
+// Lookup 返回包含地址的网段
+func (s *PrefixSet) Lookup(addr netip.Addr) (netip.Prefix, bool) {
+	if !addr.IsValid() {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	b, node := s.root(addr)
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return netip.PrefixFrom(addr, i).Masked(), true
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		node = node.children[bitAt(b, i)]
+	}
+	return netip.Prefix{}, false
+}
+
+// Len 集合中的网段数量，被其他网段覆盖的子网段不计入
+func (s *PrefixSet) Len() int {
+	return s.size
+}
+
+func (s *PrefixSet) root(addr netip.Addr) ([]byte, *prefixNode) {
+	if addr.Is4() {
+		b := addr.As4()
+		return b[:], &s.v4
+	}
+	b := addr.As16()
+	return b[:], &s.v6
+}
+
+// count 子树中的网段数量
+func (n *prefixNode) count() int {
+	if n == nil {
+		return 0
+	}
+	if n.terminal {
+		return 1
+	}
+	return n.children[0].count() + n.children[1].count()
+}
+
+func bitAt(b []byte, i int) int {
+	return int(b[i/8]>>(7-uint(i%8))) & 1
+}
@@ -0,0 +1,194 @@
+package ip
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func parsePrefixes(t *testing.T, cidrs ...string) []netip.Prefix {
+	t.Helper()
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+	}
+	return prefixes
+}
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "10.1.2.3/8", want: "10.0.0.0/8"},
+		{in: "10.1.2.3", want: "10.1.2.3/32"},
+		{in: "2001:db8::1/32", want: "2001:db8::/32"},
+		{in: "2001:db8::1", want: "2001:db8::1/128"},
+		{in: "fe80::1%eth0", want: "fe80::1/128"},
+		{in: "::ffff:10.1.2.3", want: "10.1.2.3/32"},
+		{in: "::ffff:10.0.0.0/104", want: "10.0.0.0/8"},
+		{in: "::ffff:0:0/96", want: "0.0.0.0/0"},
+		{in: "::ffff:0:0/80", wantErr: true},
+		{in: "10.0.0.0/33", wantErr: true},
+		{in: "bad", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCIDR(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCIDR(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("ParseCIDR(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCIDRContains(t *testing.T) {
+	tests := []struct {
+		cidr, ip string
+		want     bool
+	}{
+		{cidr: "10.0.0.0/8", ip: "10.1.2.3", want: true},
+		{cidr: "10.0.0.0/8", ip: "11.0.0.0", want: false},
+		{cidr: "10.0.0.0/8", ip: "::ffff:10.1.2.3", want: true},
+		{cidr: "::ffff:10.0.0.0/104", ip: "10.1.2.3", want: true},
+		{cidr: "::ffff:10.0.0.0/104", ip: "11.1.2.3", want: false},
+		{cidr: "2001:db8::/32", ip: "2001:db8:1::1", want: true},
+		{cidr: "2001:db8::/32", ip: "10.1.2.3", want: false},
+		{cidr: "fe80::/10", ip: "fe80::1%eth0", want: true},
+	}
+	for _, tt := range tests {
+		got, err := CIDRContains(tt.cidr, tt.ip)
+		if err != nil {
+			t.Errorf("CIDRContains(%q, %q) err = %v", tt.cidr, tt.ip, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CIDRContains(%q, %q) = %v, want %v", tt.cidr, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestRangeToCIDRs(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       []string
+		wantErr    bool
+	}{
+		{start: "10.0.0.1", end: "10.0.0.10",
+			want: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/31", "10.0.0.10/32"}},
+		{start: "0.0.0.0", end: "255.255.255.255", want: []string{"0.0.0.0/0"}},
+		{start: "10.0.0.0", end: "10.0.0.0", want: []string{"10.0.0.0/32"}},
+		{start: "::", end: "::5", want: []string{"::/126", "::4/127"}},
+		{start: "::ffff:10.0.0.0", end: "10.0.0.255", want: []string{"10.0.0.0/24"}},
+		{start: "fe80::1%eth0", end: "fe80::2%eth0", want: []string{"fe80::1/128", "fe80::2/128"}},
+		{start: "10.0.0.2", end: "10.0.0.1", wantErr: true},
+		{start: "10.0.0.1", end: "::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := RangeToCIDRs(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RangeToCIDRs(%s, %s) err = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, parsePrefixes(t, tt.want...)) {
+			t.Errorf("RangeToCIDRs(%s, %s) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	got, err := Subnets(netip.MustParsePrefix("10.0.0.0/24"), 26)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := parsePrefixes(t, "10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subnets = %v, want %v", got, want)
+	}
+	if _, err := Subnets(netip.MustParsePrefix("10.0.0.0/24"), 23); err == nil {
+		t.Error("Subnets with shorter bits should fail")
+	}
+	if _, err := Subnets(netip.MustParsePrefix("10.0.0.0/8"), 32); err == nil {
+		t.Error("Subnets exceeding limit should fail")
+	}
+	lo, hi, ok := SplitPrefix(netip.MustParsePrefix("::ffff:10.0.0.0/104"))
+	if !ok || lo.String() != "10.0.0.0/9" || hi.String() != "10.128.0.0/9" {
+		t.Errorf("SplitPrefix = %s, %s, %v", lo, hi, ok)
+	}
+	if _, _, ok := SplitPrefix(netip.MustParsePrefix("10.0.0.1/32")); ok {
+		t.Error("SplitPrefix of single address should fail")
+	}
+}
+
+func TestMergePrefixes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{name: "adjacent", in: []string{"10.0.1.0/24", "10.0.0.0/24"}, want: []string{"10.0.0.0/23"}},
+		{name: "overlap", in: []string{"10.0.0.0/24", "10.0.0.128/25", "10.0.0.0/16"}, want: []string{"10.0.0.0/16"}},
+		{name: "disjoint", in: []string{"192.168.0.0/16", "10.0.0.0/8"}, want: []string{"10.0.0.0/8", "192.168.0.0/16"}},
+		{name: "mixed family", in: []string{"fd00::/8", "10.0.0.0/8"}, want: []string{"10.0.0.0/8", "fd00::/8"}},
+		{name: "ipv4 mapped", in: []string{"::ffff:10.0.0.0/104", "11.0.0.0/8"}, want: []string{"10.0.0.0/7"}},
+		{name: "unaligned", in: []string{"10.0.0.5/24"}, want: []string{"10.0.0.0/24"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergePrefixes(parsePrefixes(t, tt.in...))
+			if want := parsePrefixes(t, tt.want...); !reflect.DeepEqual(got, want) {
+				t.Errorf("MergePrefixes = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPrefixSet(t *testing.T) {
+	s, err := NewPrefixSet("10.0.0.0/8", "10.1.0.0/16", "1.2.3.4", "fd00::/8", "::ffff:172.16.0.0/108")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 4 {
+		t.Errorf("Len = %d, want 4", s.Len())
+	}
+	tests := []struct {
+		ip   string
+		want string // 匹配的网段，为空表示不包含
+	}{
+		{ip: "10.9.9.9", want: "10.0.0.0/8"},
+		{ip: "10.1.2.3", want: "10.0.0.0/8"},
+		{ip: "::ffff:10.0.0.1", want: "10.0.0.0/8"},
+		{ip: "1.2.3.4", want: "1.2.3.4/32"},
+		{ip: "1.2.3.5"},
+		{ip: "172.31.255.255", want: "172.16.0.0/12"},
+		{ip: "172.32.0.0"},
+		{ip: "fd12::1", want: "fd00::/8"},
+		{ip: "fe80::1"},
+	}
+	for _, tt := range tests {
+		got, ok := s.Lookup(netip.MustParseAddr(tt.ip))
+		if ok != (tt.want != "") || (ok && got.String() != tt.want) {
+			t.Errorf("Lookup(%s) = %s, %v, want %q", tt.ip, got, ok, tt.want)
+		}
+		if s.ContainsString(tt.ip) != ok {
+			t.Errorf("ContainsString(%s) inconsistent with Lookup", tt.ip)
+		}
+	}
+	if s.ContainsString("bad") {
+		t.Error("ContainsString(bad) = true")
+	}
+	// 后加入更大的网段会覆盖已有子网段
+	s2, _ := NewPrefixSet("10.1.0.0/16", "10.2.0.0/16")
+	if err := s2.Add(netip.MustParsePrefix("10.0.0.0/8")); err != nil || s2.Len() != 1 {
+		t.Errorf("Add covering prefix err = %v, Len = %d, want 1", err, s2.Len())
+	}
+	if err := s2.Add(netip.MustParsePrefix("::ffff:0:0/80")); err == nil {
+		t.Error("Add short ipv4-mapped prefix should fail")
+	}
+	if _, err := NewPrefixSet("bad"); err == nil {
+		t.Error("NewPrefixSet(bad) should fail")
+	}
+}
@@ -0,0 +1,124 @@
+package ip
+
+import (
+	"net/netip"
+)
+
+var (
+	// privatePrefixes 私有地址，RFC1918及IPv6唯一本地地址RFC4193
+	privatePrefixes = mustPrefixSet("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+	// cgnatPrefixes 运营商级NAT共享地址，RFC6598
+	cgnatPrefixes = mustPrefixSet("100.64.0.0/10")
+	// documentationPrefixes 文档示例地址，RFC5737、RFC3849、RFC9637
+	documentationPrefixes = mustPrefixSet("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24",
+		"2001:db8::/32", "3fff::/20")
+	// bogonPrefixes 不应出现在公网上的地址，包括私有、保留及特殊用途地址；
+	// IPv4映射的IPv6地址::ffff:0:0/96查询前会转换为IPv4，按IPv4网段判断，无需单独列出
+	bogonPrefixes = mustPrefixSet(
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.0.0.0/24", "192.0.2.0/24", "192.168.0.0/16", "198.18.0.0/15", "198.51.100.0/24",
+		"203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "64:ff9b:1::/48", "100::/64", "2001:2::/48",
+		"2001:10::/28", "2001:db8::/32", "3fff::/20", "fc00::/7", "fe80::/10", "fec0::/10", "ff00::/8",
+	)
+	// globalUnicastV6 当前分配的IPv6全球单播地址
+	globalUnicastV6 = netip.MustParsePrefix("2000::/3")
+	// nat64Prefix NAT64知名前缀，低32位内嵌IPv4地址，RFC6052
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	// sixToFourPrefix 6to4前缀，第16到48位内嵌IPv4地址，RFC3056
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")
+	// teredoPrefix Teredo前缀，第32到64位为服务端IPv4，低32位为按位取反的客户端IPv4，RFC4380
+	teredoPrefix = netip.MustParsePrefix("2001::/32")
+)
+
+func mustPrefixSet(cidrs ...string) *PrefixSet {
+	s, err := NewPrefixSet(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// IsPrivate 是否为私有地址，IPv4为RFC1918地址，IPv6为唯一本地地址fc00::/7
+func IsPrivate(addr netip.Addr) bool {
+	return privatePrefixes.Contains(addr)
+}
+
+// IsLoopback 是否为回环地址，127.0.0.0/8或::1
+func IsLoopback(addr netip.Addr) bool {
+	return addr.Unmap().IsLoopback()
+}
+
+// IsLinkLocal 是否为链路本地地址，包括单播169.254.0.0/16、fe80::/10及对应的组播地址
+func IsLinkLocal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast()
+}
+
+// IsCGNAT 是否为运营商级NAT共享地址100.64.0.0/10
+func IsCGNAT(addr netip.Addr) bool {
+	return cgnatPrefixes.Contains(addr)
+}
+
+// IsMulticast 是否为组播地址，224.0.0.0/4或ff00::/8
+func IsMulticast(addr netip.Addr) bool {
+	return addr.Unmap().IsMulticast()
+}
+
+// IsDocumentation 是否为文档示例地址，如192.0.2.0/24、2001:db8::/32
+func IsDocumentation(addr netip.Addr) bool {
+	return documentationPrefixes.Contains(addr)
+}
+
+// IsBogon 是否为不应出现在公网上的地址，包括私有、回环、链路本地、CGNAT、组播、文档、保留地址，
+// IPv6不在全球单播地址2000::/3内的也视为bogon，非法地址返回true
+func IsBogon(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return true
+	}
+	addr = addr.Unmap()
+	if addr.Is6() && !globalUnicastV6.Contains(addr) && !nat64Prefix.Contains(addr) {
+		return true
+	}
+	return bogonPrefixes.Contains(addr)
+}
+
+// IsPublic 是否为公网可路由地址，NAT64、6to4、Teredo地址按内嵌的IPv4地址判断，可用于防范SSRF
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if IsBogon(addr) {
+		return false
+	}
+	for _, v4 := range embeddedIPv4(addr) {
+		if IsBogon(v4) {
+			return false
+		}
+	}
+	return true
+}
+
+// embeddedIPv4 返回NAT64、6to4、Teredo地址中内嵌的IPv4地址，其它地址返回nil
+func embeddedIPv4(addr netip.Addr) []netip.Addr {
+	b := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return []netip.Addr{netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})}
+	case sixToFourPrefix.Contains(addr):
+		return []netip.Addr{netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]})}
+	case teredoPrefix.Contains(addr):
+		return []netip.Addr{
+			netip.AddrFrom4([4]byte{b[4], b[5], b[6], b[7]}),
+			netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]}),
+		}
+	}
+	return nil
+}
+
+// IsPublicIP 同IsPublic，ip字符串非法时返回false
+func IsPublicIP(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return IsPublic(addr)
+}
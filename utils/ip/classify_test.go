@@ -0,0 +1,77 @@
+package ip
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsBogon(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "8.8.8.8", want: false},
+		{in: "10.1.2.3", want: true},
+		{in: "100.64.0.1", want: true},
+		{in: "127.0.0.1", want: true},
+		{in: "169.254.1.1", want: true},
+		{in: "192.0.2.1", want: true},
+		{in: "198.18.0.1", want: true},
+		{in: "224.0.0.1", want: true},
+		{in: "255.255.255.255", want: true},
+		// IPv4映射地址按IPv4判断，::ffff:0:0/96不再整体视为bogon
+		{in: "::ffff:8.8.8.8", want: false},
+		{in: "::ffff:10.1.2.3", want: true},
+		{in: "::ffff:127.0.0.1", want: true},
+		{in: "2606:4700::1111", want: false},
+		{in: "::", want: true},
+		{in: "::1", want: true},
+		{in: "64:ff9b:1::1", want: true},
+		{in: "2001:db8::1", want: true},
+		{in: "3fff::1", want: true},
+		{in: "fc00::1", want: true},
+		{in: "fe80::1", want: true},
+		{in: "ff02::1", want: true},
+		{in: "4000::1", want: true},
+		{in: "64:ff9b::808:808", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsBogon(netip.MustParseAddr(tt.in)); got != tt.want {
+			t.Errorf("IsBogon(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if !IsBogon(netip.Addr{}) {
+		t.Errorf("IsBogon(invalid) = false, want true")
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "8.8.8.8", want: true},
+		{in: "::ffff:8.8.8.8", want: true},
+		{in: "::ffff:127.0.0.1", want: false},
+		{in: "2606:4700::1111", want: true},
+		// NAT64内嵌IPv4
+		{in: "64:ff9b::808:808", want: true},
+		{in: "64:ff9b::7f00:1", want: false},
+		{in: "64:ff9b::a00:1", want: false},
+		// 6to4内嵌IPv4
+		{in: "2002:808:808::1", want: true},
+		{in: "2002:7f00:1::1", want: false},
+		{in: "2002:a00:1::1", want: false},
+		// Teredo服务端IPv4及取反的客户端IPv4
+		{in: "2001:0:808:808::f7f7:f7f7", want: true},
+		{in: "2001:0:808:808::80ff:fffe", want: false},
+		{in: "2001:0:808:808::f5ff:fffe", want: false},
+		{in: "2001:0:a00:1::f7f7:f7f7", want: false},
+		{in: "bad", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicIP(tt.in); got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
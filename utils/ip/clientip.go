@@ -0,0 +1,110 @@
+package ip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// 代理转发客户端ip的请求头
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+type clientIPConfig struct {
+	header string // 读取客户端地址的转发请求头
+}
+
+type clientIPOption func(c *clientIPConfig)
+
+// ClientIPOptionWithHeader 设置读取客户端地址的转发请求头，默认HeaderXForwardedFor；
+// 只应设置为可信代理会追加或覆盖的请求头，代理原样透传的请求头可被客户端伪造，
+// 例如nginx、ALB只追加X-Forwarded-For而透传Forwarded，此时不能使用HeaderForwarded
+func ClientIPOptionWithHeader(header string) clientIPOption {
+	return func(c *clientIPConfig) {
+		c.header = header
+	}
+}
+
+// ClientIP 获取请求的客户端ip，trustedProxies为可信代理网段，为nil表示不信任任何代理
+// 只有直连地址RemoteAddr属于可信代理时才读取转发请求头，且只读取ClientIPOptionWithHeader指定的一个请求头，
+// 从右往左跳过可信代理地址，返回第一个不可信的地址；遇到无法解析的地址时返回其右侧最近的代理地址
+func ClientIP(r *http.Request, trustedProxies *PrefixSet, opts ...clientIPOption) string {
+	addr := ClientAddr(r, trustedProxies, opts...)
+	if !addr.IsValid() {
+		return ""
+	}
+	return addr.String()
+}
+
+// ClientAddr 同ClientIP，返回netip.Addr，RemoteAddr无法解析时返回零值
+func ClientAddr(r *http.Request, trustedProxies *PrefixSet, opts ...clientIPOption) netip.Addr {
+	c := &clientIPConfig{header: HeaderXForwardedFor}
+	for _, opt := range opts {
+		opt(c)
+	}
+	remote := parseHost(r.RemoteAddr)
+	if !remote.IsValid() || !trusted(trustedProxies, remote) {
+		return remote
+	}
+	var chain []string
+	if http.CanonicalHeaderKey(c.header) == HeaderForwarded {
+		chain = parseForwarded(r.Header.Values(HeaderForwarded))
+	} else {
+		for _, value := range r.Header.Values(c.header) { // X-Forwarded-For为逗号分隔列表，X-Real-IP为单个地址
+			chain = append(chain, strings.Split(value, ",")...)
+		}
+	}
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr := parseHost(strings.TrimSpace(chain[i]))
+		if !addr.IsValid() {
+			return client
+		}
+		client = addr
+		if !trusted(trustedProxies, addr) {
+			return addr
+		}
+	}
+	return client
+}
+
+func trusted(trustedProxies *PrefixSet, addr netip.Addr) bool {
+	return trustedProxies != nil && trustedProxies.Contains(addr)
+}
+
+// parseForwarded 解析RFC 7239 Forwarded请求头，按顺序返回每一跳的for值
+// 如: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func parseForwarded(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			node := ""
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					node = strings.Trim(v, `"`)
+				}
+			}
+			chain = append(chain, node)
+		}
+	}
+	return chain
+}
+
+// parseHost 解析"ip"、"ip:port"、"[ipv6]"、"[ipv6]:port"格式的地址，去掉IPv6的zone
+func parseHost(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	} else {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap().WithZone("")
+}
@@ -0,0 +1,56 @@
+package ip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := NewPrefixSet("10.0.0.0/8", "2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		remote  string
+		header  http.Header
+		opts    []clientIPOption
+		proxies *PrefixSet
+		want    string
+	}{
+		{name: "untrusted remote ignores headers", remote: "1.1.1.1:80",
+			header: http.Header{"X-Forwarded-For": {"9.9.9.9"}}, proxies: trusted, want: "1.1.1.1"},
+		{name: "no trusted proxies", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"9.9.9.9"}}, want: "10.0.0.1"},
+		{name: "skip trusted hops", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"6.6.6.6, 9.9.9.9, 10.1.1.1"}}, proxies: trusted, want: "9.9.9.9"},
+		{name: "multiple xff headers", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"6.6.6.6", "9.9.9.9"}}, proxies: trusted, want: "9.9.9.9"},
+		{name: "all trusted", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"10.2.2.2, 10.1.1.1"}}, proxies: trusted, want: "10.2.2.2"},
+		{name: "invalid hop", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"garbage, 10.1.1.1"}}, proxies: trusted, want: "10.1.1.1"},
+		{name: "forwarded ignored by default", remote: "10.0.0.1:80",
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.9"}, "Forwarded": {"for=6.6.6.6"}},
+			proxies: trusted, want: "203.0.113.9"},
+		{name: "forwarded header", remote: "[2001:db8::1]:80",
+			header: http.Header{"Forwarded": {`for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`},
+				"X-Forwarded-For": {"6.6.6.6"}},
+			opts: []clientIPOption{ClientIPOptionWithHeader(HeaderForwarded)}, proxies: trusted, want: "192.0.2.60"},
+		{name: "x-real-ip header", remote: "10.0.0.1:80",
+			header: http.Header{"X-Real-Ip": {"5.5.5.5"}, "X-Forwarded-For": {"6.6.6.6"}},
+			opts:   []clientIPOption{ClientIPOptionWithHeader(HeaderXRealIP)}, proxies: trusted, want: "5.5.5.5"},
+		{name: "configured header missing", remote: "10.0.0.1:80",
+			header: http.Header{"X-Forwarded-For": {"6.6.6.6"}},
+			opts:   []clientIPOption{ClientIPOptionWithHeader(HeaderXRealIP)}, proxies: trusted, want: "10.0.0.1"},
+		{name: "invalid remote", remote: "bad", proxies: trusted, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: tt.remote, Header: tt.header}
+			if got := ClientIP(r, tt.proxies, tt.opts...); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package ip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// ParseIPv4U32 解析IPv4字符串并按order字节序转为uint32，非法或非IPv4地址返回错误
+// 网络字节序使用binary.BigEndian，与IPv4ToU32一致；C服务直接把in_addr当作整数存储时在小端机器上为binary.LittleEndian
+func ParseIPv4U32(ip string, order binary.ByteOrder) (uint32, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return 0, err
+	}
+	return AddrToU32(addr, order)
+}
+
+// AddrToU32 IPv4地址按order字节序转为uint32，IPv4映射的IPv6地址按IPv4处理，其他地址返回错误
+func AddrToU32(addr netip.Addr, order binary.ByteOrder) (uint32, error) {
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return 0, fmt.Errorf("%s is not ipv4", addr)
+	}
+	b := addr.As4()
+	return order.Uint32(b[:]), nil
+}
+
+// U32ToAddr uint32按order字节序转为IPv4地址
+func U32ToAddr(ip uint32, order binary.ByteOrder) netip.Addr {
+	var b [4]byte
+	order.PutUint32(b[:], ip)
+	return netip.AddrFrom4(b)
+}
+
+// IPToAddr net.IP转netip.Addr，IPv4映射的IPv6地址转为IPv4，非法ip返回false
+func IPToAddr(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// AddrToIP netip.Addr转net.IP，零值返回nil
+func AddrToIP(addr netip.Addr) net.IP {
+	if !addr.IsValid() {
+		return nil
+	}
+	return net.IP(addr.AsSlice())
+}
+
+// ParseIPv4U32s 批量解析IPv4字符串，任一非法时返回错误及其下标
+func ParseIPv4U32s(ips []string, order binary.ByteOrder) ([]uint32, error) {
+	result := make([]uint32, len(ips))
+	for i, ip := range ips {
+		v, err := ParseIPv4U32(ip, order)
+		if err != nil {
+			return nil, fmt.Errorf("parse ips[%d] fail, err: %v", i, err)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// U32sToAddrs 批量把uint32按order字节序转为IPv4地址
+func U32sToAddrs(ips []uint32, order binary.ByteOrder) []netip.Addr {
+	result := make([]netip.Addr, len(ips))
+	for i, ip := range ips {
+		result[i] = U32ToAddr(ip, order)
+	}
+	return result
+}
+
+// U32sToStrings 批量把uint32按order字节序转为IPv4字符串
+func U32sToStrings(ips []uint32, order binary.ByteOrder) []string {
+	result := make([]string, len(ips))
+	for i, ip := range ips {
+		result[i] = U32ToAddr(ip, order).String()
+	}
+	return result
+}
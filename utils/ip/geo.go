@@ -0,0 +1,350 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ErrGeoNotFound ip库中没有该ip的记录
+var ErrGeoNotFound = errors.New("ip not found in geo database")
+
+// ip库文件格式
+const (
+	GeoFormatMMDB = "mmdb" // MaxMind DB格式，如GeoLite2-City.mmdb、GeoLite2-ASN.mmdb
+	GeoFormatCSV  = "csv"  // ip段CSV格式，每行: start,end,country,region,city,asn,as_org
+)
+
+// GeoInfo ip地理位置及ASN信息，ip库中没有的字段为空
+type GeoInfo struct {
+	Country string // 国家ISO代码，如CN
+	Region  string // 省份/州
+	City    string // 城市
+	ASN     uint32 // 自治系统号
+	ASOrg   string // 自治系统所属组织
+}
+
+// geoDB 加载到内存中的ip库
+type geoDB interface {
+	lookup(addr netip.Addr) (*GeoInfo, error)
+}
+
+type geoLocator struct {
+	format   string        // 文件格式，为空时按扩展名判断，.mmdb为mmdb，其他为csv
+	language string        // mmdb地名语言，默认en
+	interval time.Duration // 检查文件更新的间隔，0表示不热加载
+	onError  func(err error)
+}
+
+type geoOption func(l *geoLocator)
+
+// GeoOptionWithFormat 设置ip库文件格式GeoFormatMMDB或GeoFormatCSV，默认按扩展名判断
+func GeoOptionWithFormat(format string) geoOption {
+	return func(l *geoLocator) {
+		l.format = format
+	}
+}
+
+// GeoOptionWithLanguage 设置mmdb中地名的语言，如zh-CN，缺少该语言时使用en
+func GeoOptionWithLanguage(language string) geoOption {
+	return func(l *geoLocator) {
+		l.language = language
+	}
+}
+
+// GeoOptionWithReloadInterval 设置检查文件修改时间的间隔，文件变化后重新加载，默认1分钟，0表示不热加载
+func GeoOptionWithReloadInterval(interval time.Duration) geoOption {
+	return func(l *geoLocator) {
+		l.interval = interval
+	}
+}
+
+// GeoOptionWithErrorHandler 设置热加载失败的处理函数，加载失败时继续使用旧的ip库
+func GeoOptionWithErrorHandler(f func(err error)) geoOption {
+	return func(l *geoLocator) {
+		l.onError = f
+	}
+}
+
+// GeoLocator 离线ip库，整个文件加载到内存中查询，支持文件变化后热加载，可并发使用
+type GeoLocator struct {
+	opts    geoLocator
+	path    string
+	db      atomic.Value // geoDB
+	lock    sync.Mutex   // 保护modTime，避免Reload与热加载协程并发加载
+	modTime time.Time
+}
+
+// NewGeoLocator 从本地文件加载ip库，ctx结束后停止热加载
+func NewGeoLocator(ctx context.Context, path string, opts ...geoOption) (*GeoLocator, error) {
+	l := &GeoLocator{
+		opts: geoLocator{language: "en", interval: time.Minute, onError: func(error) {}},
+		path: path,
+	}
+	for _, opt := range opts {
+		opt(&l.opts)
+	}
+	if l.opts.format == "" {
+		l.opts.format = GeoFormatCSV
+		if strings.EqualFold(filepath.Ext(path), ".mmdb") {
+			l.opts.format = GeoFormatMMDB
+		}
+	}
+	if _, err := l.reload(); err != nil {
+		return nil, err
+	}
+	if l.opts.interval > 0 {
+		go l.watch(ctx)
+	}
+	return l, nil
+}
+
+// Lookup 查询ip字符串的地理位置
+func (l *GeoLocator) Lookup(ip string) (*GeoInfo, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+	return l.LookupAddr(addr)
+}
+
+// LookupAddr 查询地址的地理位置，没有记录时返回ErrGeoNotFound
+func (l *GeoLocator) LookupAddr(addr netip.Addr) (*GeoInfo, error) {
+	return l.db.Load().(geoDB).lookup(addr.Unmap())
+}
+
+// LookupU32 查询IPv4ToU32转换后的uint32 ip的地理位置
+func (l *GeoLocator) LookupU32(ip uint32) (*GeoInfo, error) {
+	return l.LookupAddr(U32ToAddr(ip, binary.BigEndian))
+}
+
+// Reload 立即检查文件，修改时间变化时重新加载，返回是否重新加载
+func (l *GeoLocator) Reload() (bool, error) {
+	return l.reload()
+}
+
+func (l *GeoLocator) watch(ctx context.Context) {
+	ticker := time.NewTicker(l.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.reload(); err != nil {
+				l.opts.onError(err)
+			}
+		}
+	}
+}
+
+func (l *GeoLocator) reload() (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, fmt.Errorf("stat geo file %s fail, err: %v", l.path, err)
+	}
+	if info.ModTime().Equal(l.modTime) {
+		return false, nil
+	}
+	// 无论加载是否成功都记录修改时间，文件损坏时不会每次检查都重复加载
+	l.modTime = info.ModTime()
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return false, fmt.Errorf("read geo file %s fail, err: %v", l.path, err)
+	}
+	var db geoDB
+	switch l.opts.format {
+	case GeoFormatMMDB:
+		db, err = newMMDB(data, l.opts.language)
+	case GeoFormatCSV:
+		db, err = newCSVDB(bytes.NewReader(data))
+	default:
+		err = fmt.Errorf("unknown geo format %s", l.opts.format)
+	}
+	if err != nil {
+		return false, fmt.Errorf("load geo file %s fail, err: %v", l.path, err)
+	}
+	l.db.Store(db)
+	return true, nil
+}
+
+type mmdb struct {
+	reader   *maxminddb.Reader
+	language string
+}
+
+// mmdbRecord 兼容GeoIP2/GeoLite2的City、Country及ASN库字段
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN   uint32 `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+func newMMDB(data []byte, language string) (*mmdb, error) {
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdb{reader: reader, language: language}, nil
+}
+
+func (m *mmdb) lookup(addr netip.Addr) (*GeoInfo, error) {
+	var record mmdbRecord
+	_, ok, err := m.reader.LookupNetwork(net.IP(addr.AsSlice()), &record)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrGeoNotFound
+	}
+	info := &GeoInfo{
+		Country: record.Country.ISOCode,
+		City:    m.name(record.City.Names),
+		ASN:     record.ASN,
+		ASOrg:   record.ASOrg,
+	}
+	if len(record.Subdivisions) > 0 {
+		info.Region = m.name(record.Subdivisions[0].Names)
+	}
+	return info, nil
+}
+
+func (m *mmdb) name(names map[string]string) string {
+	if name, ok := names[m.language]; ok {
+		return name
+	}
+	return names["en"]
+}
+
+// csvDB 按起始地址排序的ip段，二分查找
+type csvDB struct {
+	v4, v6 []geoRange
+}
+
+type geoRange struct {
+	start, end netip.Addr
+	info       *GeoInfo
+}
+
+// newCSVDB 解析ip段CSV，start、end可以是ip字符串或者IPv4ToU32转换后的整数，
+// 以#开头的行为注释，首行start无法解析时视为表头，ip段不能重叠
+func newCSVDB(r io.Reader) (*csvDB, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	db := &csvDB{}
+	for line := 1; ; line++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: need at least start,end,country", line)
+		}
+		start, err := parseGeoAddr(fields[0])
+		if err != nil && line == 1 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		end, err := parseGeoAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: invalid range %s-%s", line, start, end)
+		}
+		info := &GeoInfo{Country: fields[2]}
+		if len(fields) > 3 {
+			info.Region = fields[3]
+		}
+		if len(fields) > 4 {
+			info.City = fields[4]
+		}
+		if len(fields) > 5 && fields[5] != "" {
+			asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(fields[5]), "AS"), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid asn %s", line, fields[5])
+			}
+			info.ASN = uint32(asn)
+		}
+		if len(fields) > 6 {
+			info.ASOrg = fields[6]
+		}
+		if start.Is4() {
+			db.v4 = append(db.v4, geoRange{start: start, end: end, info: info})
+		} else {
+			db.v6 = append(db.v6, geoRange{start: start, end: end, info: info})
+		}
+	}
+	for _, ranges := range [][]geoRange{db.v4, db.v6} {
+		sort.Slice(ranges, func(i, j int) bool {
+			return ranges[i].start.Less(ranges[j].start)
+		})
+		for i := 1; i < len(ranges); i++ {
+			if !ranges[i-1].end.Less(ranges[i].start) {
+				return nil, fmt.Errorf("range %s-%s overlaps %s-%s", ranges[i-1].start, ranges[i-1].end,
+					ranges[i].start, ranges[i].end)
+			}
+		}
+	}
+	return db, nil
+}
+
+func parseGeoAddr(s string) (netip.Addr, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return U32ToAddr(uint32(n), binary.BigEndian), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+func (c *csvDB) lookup(addr netip.Addr) (*GeoInfo, error) {
+	ranges := c.v6
+	if addr.Is4() {
+		ranges = c.v4
+	}
+	// 第一个end不小于addr的ip段
+	i := sort.Search(len(ranges), func(i int) bool {
+		return !ranges[i].end.Less(addr)
+	})
+	if i < len(ranges) && !addr.Less(ranges[i].start) {
+		info := *ranges[i].info // 返回副本，避免调用方修改共享记录
+		return &info, nil
+	}
+	return nil, ErrGeoNotFound
+}
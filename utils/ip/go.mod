@@ -1,3 +1,7 @@
 module github.com/jensenguo/project-go/utils/ip
 
-go 1.17
+go 1.19
+
+require github.com/oschwald/maxminddb-golang v1.12.0
+
+require golang.org/x/sys v0.10.0 // indirect
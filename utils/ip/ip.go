@@ -7,7 +7,7 @@ import (
 	"net"
 )
 
-// GetInterfaceIP 通过指定的网卡名称获取对应的ip地址
+// GetInterfaceIP 通过指定的网卡名称获取对应的IPv4地址，IPv6地址使用GetInterfaceIPv6
 func GetInterfaceIP(name string) (string, error) {
 	itf, err := net.InterfaceByName(name)
 	if err != nil {
@@ -28,20 +28,17 @@ func GetInterfaceIP(name string) (string, error) {
 	return "", fmt.Errorf("get net %s ip fail", name)
 }
 
-// LocalIP 默认网卡0对应的ip地址
+// LocalIP 本机ip地址，使用默认规则探测，探测失败返回空字符串，需要错误信息或自定义规则使用DetectLocalIP
 func LocalIP() string {
-	ip, _ := GetInterfaceIP("eth0")
+	ip, _ := DetectLocalIP()
 	return ip
 }
 
-// Uint32ToIPv4 uint32 转 ipv4
+// Uint32ToIPv4 uint32 转 ipv4，uint32为网络字节序，其他字节序使用U32ToAddr
 func Uint32ToIPv4(intIP uint32) net.IP {
-	var bytes [4]byte
-	bytes[0] = byte(intIP & 0xFF)
-	bytes[1] = byte((intIP >> 8) & 0xFF)
-	bytes[2] = byte((intIP >> 16) & 0xFF)
-	bytes[3] = byte((intIP >> 24) & 0xFF)
-	return net.IPv4(bytes[3], bytes[2], bytes[1], bytes[0])
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, intIP)
+	return ip.To16()
 }
 
 // IPv4ToString IP 转字符串
@@ -50,13 +47,11 @@ func IPv4ToString(ipInt uint32) string {
 	return ip.String()
 }
 
-// IPv4ToU32 ip 转 uint32
+// IPv4ToU32 ip 转 uint32，非法及非IPv4地址返回0，需要区分错误使用ParseIPv4U32，IPv6地址使用IPv6ToBigInt或AddrToUint128
 func IPv4ToU32(ip string) uint32 {
-	ips := net.ParseIP(ip)
-	if len(ips) == 16 {
-		return binary.BigEndian.Uint32(ips[12:16])
-	} else if len(ips) == 4 {
-		return binary.BigEndian.Uint32(ips)
+	ips := net.ParseIP(ip).To4()
+	if ips == nil {
+		return 0
 	}
-	return 0
+	return binary.BigEndian.Uint32(ips)
 }
@@ -0,0 +1,140 @@
+package ip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+)
+
+// Family 双栈环境下的地址族选择策略
+type Family int
+
+const (
+	FamilyIPv4First Family = iota // 优先IPv4，没有时使用IPv6
+	FamilyIPv6First               // 优先IPv6，没有时使用IPv4
+	FamilyIPv4Only                // 只使用IPv4
+	FamilyIPv6Only                // 只使用IPv6
+)
+
+// GetInterfaceAddrs 获取网卡上的所有ip地址，IPv4映射的IPv6地址会转换为IPv4
+func GetInterfaceAddrs(name string) ([]netip.Addr, error) {
+	itf, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return interfaceAddrs(itf)
+}
+
+func interfaceAddrs(itf *net.Interface) ([]netip.Addr, error) {
+	items, err := itf.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]netip.Addr, 0, len(items))
+	for _, item := range items {
+		v, ok := item.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if addr, ok := netip.AddrFromSlice(v.IP); ok {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+	return addrs, nil
+}
+
+// GetInterfaceIPv6 通过指定的网卡名称获取对应的IPv6地址，跳过链路本地地址（fe80::/10）
+func GetInterfaceIPv6(name string) (string, error) {
+	addrs, err := GetInterfaceAddrs(name)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if addr.Is6() && !addr.IsLinkLocalUnicast() && !addr.IsLoopback() {
+			return addr.String(), nil
+		}
+	}
+	return "", fmt.Errorf("get net %s ipv6 fail", name)
+}
+
+// GetInterfacePreferredIP 按family策略获取网卡地址，跳过链路本地地址
+func GetInterfacePreferredIP(name string, family Family) (string, error) {
+	addrs, err := GetInterfaceAddrs(name)
+	if err != nil {
+		return "", err
+	}
+	addr, ok := SelectPreferred(addrs, family)
+	if !ok {
+		return "", fmt.Errorf("get net %s ip fail", name)
+	}
+	return addr.String(), nil
+}
+
+// SelectPreferred 从地址列表中按family策略选择第一个可用地址，跳过链路本地地址和无效地址
+func SelectPreferred(addrs []netip.Addr, family Family) (netip.Addr, bool) {
+	var v4, v6 netip.Addr
+	for _, addr := range addrs {
+		if !addr.IsValid() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.Is4() && !v4.IsValid() {
+			v4 = addr
+		} else if addr.Is6() && !v6.IsValid() {
+			v6 = addr
+		}
+	}
+	var candidates []netip.Addr
+	switch family {
+	case FamilyIPv4First:
+		candidates = []netip.Addr{v4, v6}
+	case FamilyIPv6First:
+		candidates = []netip.Addr{v6, v4}
+	case FamilyIPv4Only:
+		candidates = []netip.Addr{v4}
+	case FamilyIPv6Only:
+		candidates = []netip.Addr{v6}
+	}
+	for _, addr := range candidates {
+		if addr.IsValid() {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// AddrToUint128 IPv6地址转128位整数，hi为高64位，lo为低64位，IPv4按IPv4映射地址转换
+func AddrToUint128(addr netip.Addr) (hi, lo uint64) {
+	b := addr.As16()
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+}
+
+// Uint128ToAddr 128位整数转IPv6地址
+func Uint128ToAddr(hi, lo uint64) netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+	return netip.AddrFrom16(b)
+}
+
+// IPv6ToBigInt IPv6地址字符串转大整数
+func IPv6ToBigInt(ip string) (*big.Int, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+	b := addr.As16()
+	return new(big.Int).SetBytes(b[:]), nil
+}
+
+// BigIntToIPv6 大整数转IPv6地址，超出128位或为负数时返回错误
+func BigIntToIPv6(i *big.Int) (netip.Addr, error) {
+	if i.Sign() < 0 || i.BitLen() > 128 {
+		return netip.Addr{}, fmt.Errorf("%s out of ipv6 range", i)
+	}
+	var b [16]byte
+	i.FillBytes(b[:])
+	return netip.AddrFrom16(b), nil
+}
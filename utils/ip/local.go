@@ -0,0 +1,242 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path"
+	"sort"
+)
+
+// envLocalIP 指定本机ip的环境变量，设置后优先使用
+var envLocalIP = "LOCAL_IP"
+
+// defaultExclude 默认排除的虚拟网卡名称
+var defaultExclude = []string{"docker*", "veth*", "br-*", "virbr*", "cni*", "flannel*", "cali*",
+	"tunl*", "kube-*", "vxlan*", "dummy*"}
+
+type detector struct {
+	env     string         // 环境变量名，为空则不读取
+	include []string       // 只使用名称匹配的网卡，为空不限制
+	exclude []string       // 排除名称匹配的网卡
+	prefer  []netip.Prefix // 优先选择的网段，越靠前优先级越高
+	family  Family         // 地址族策略
+	dial    []string       // 网卡枚举失败时用于UDP探测路由的目标地址，不会真正发包
+}
+
+type detectOption func(d *detector) error
+
+// DetectOptionWithEnv 设置指定本机ip的环境变量名，默认LOCAL_IP，为空表示不读取环境变量
+func DetectOptionWithEnv(key string) detectOption {
+	return func(d *detector) error {
+		d.env = key
+		return nil
+	}
+}
+
+// DetectOptionWithInclude 只使用名称匹配的网卡，支持path.Match通配符，如"eth*"、"bond0"
+func DetectOptionWithInclude(patterns ...string) detectOption {
+	return func(d *detector) error {
+		if err := checkPatterns(patterns); err != nil {
+			return err
+		}
+		d.include = patterns
+		return nil
+	}
+}
+
+// DetectOptionWithExclude 排除名称匹配的网卡，会替换默认排除列表（docker*、veth*等容器虚拟网卡）
+func DetectOptionWithExclude(patterns ...string) detectOption {
+	return func(d *detector) error {
+		if err := checkPatterns(patterns); err != nil {
+			return err
+		}
+		d.exclude = patterns
+		return nil
+	}
+}
+
+// DetectOptionWithPreferCIDR 优先选择落在指定网段内的地址，越靠前优先级越高
+func DetectOptionWithPreferCIDR(cidrs ...string) detectOption {
+	return func(d *detector) error {
+		for _, cidr := range cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return fmt.Errorf("parse prefer cidr %s fail, err: %v", cidr, err)
+			}
+			d.prefer = append(d.prefer, prefix.Masked())
+		}
+		return nil
+	}
+}
+
+// DetectOptionWithFamily 设置地址族策略，默认FamilyIPv4First
+func DetectOptionWithFamily(family Family) detectOption {
+	return func(d *detector) error {
+		if family < FamilyIPv4First || family > FamilyIPv6Only {
+			return fmt.Errorf("unknown family %d", family)
+		}
+		d.family = family
+		return nil
+	}
+}
+
+// DetectOptionWithDialTarget 设置UDP探测路由的目标地址，默认8.8.8.8:53和[2001:4860:4860::8888]:53
+func DetectOptionWithDialTarget(targets ...string) detectOption {
+	return func(d *detector) error {
+		for _, target := range targets {
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				return fmt.Errorf("invalid dial target %s, err: %v", target, err)
+			}
+		}
+		d.dial = targets
+		return nil
+	}
+}
+
+// checkPatterns 检查网卡名称通配符是否合法
+func checkPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %s, err: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// DetectLocalIP 探测本机ip，依次尝试:
+// 1. 环境变量指定的ip
+// 2. 枚举所有启用的非回环网卡，按网卡名称过滤后，按优先网段、地址族、网卡顺序选择
+// 3. 通过UDP连接探测默认路由的出口地址
+// 选项不合法时返回错误
+func DetectLocalIP(opts ...detectOption) (string, error) {
+	d := &detector{env: envLocalIP, exclude: defaultExclude, family: FamilyIPv4First,
+		dial: []string{"8.8.8.8:53", "[2001:4860:4860::8888]:53"}}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return "", fmt.Errorf("apply option fail, err: %v", err)
+		}
+	}
+	if d.env != "" {
+		if v := os.Getenv(d.env); v != "" {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return "", fmt.Errorf("parse env %s ip fail, err: %v", d.env, err)
+			}
+			return addr.Unmap().String(), nil
+		}
+	}
+	if addr, ok := d.fromInterfaces(); ok {
+		return addr.String(), nil
+	}
+	if addr, ok := d.fromRoute(); ok {
+		return addr.String(), nil
+	}
+	return "", fmt.Errorf("detect local ip fail")
+}
+
+type candidate struct {
+	addr  netip.Addr
+	order int
+}
+
+func (d *detector) fromInterfaces() (netip.Addr, bool) {
+	itfs, err := net.Interfaces()
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	var candidates []candidate
+	for _, itf := range itfs {
+		if itf.Flags&net.FlagUp == 0 || itf.Flags&net.FlagLoopback != 0 || !d.match(itf.Name) {
+			continue
+		}
+		addrs, err := interfaceAddrs(&itf)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if d.usable(addr) {
+				candidates = append(candidates, candidate{addr: addr, order: len(candidates)})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return netip.Addr{}, false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		pi, pj := d.preferRank(candidates[i].addr), d.preferRank(candidates[j].addr)
+		if pi != pj {
+			return pi < pj
+		}
+		return d.familyRank(candidates[i].addr) < d.familyRank(candidates[j].addr)
+	})
+	return candidates[0].addr, true
+}
+
+func (d *detector) fromRoute() (netip.Addr, bool) {
+	for _, target := range d.dial {
+		conn, err := net.Dial("udp", target)
+		if err != nil {
+			continue
+		}
+		local, ok := conn.LocalAddr().(*net.UDPAddr)
+		conn.Close()
+		if !ok {
+			continue
+		}
+		if addr, ok := netip.AddrFromSlice(local.IP); ok && d.usable(addr.Unmap()) {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// match 网卡名称是否满足include/exclude规则
+func (d *detector) match(name string) bool {
+	for _, pattern := range d.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+	if len(d.include) == 0 {
+		return true
+	}
+	for _, pattern := range d.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// usable 地址是否可作为本机ip，排除回环、链路本地及不符合地址族策略的地址
+func (d *detector) usable(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() ||
+		addr.IsMulticast() {
+		return false
+	}
+	switch d.family {
+	case FamilyIPv4Only:
+		return addr.Is4()
+	case FamilyIPv6Only:
+		return addr.Is6()
+	}
+	return true
+}
+
+func (d *detector) preferRank(addr netip.Addr) int {
+	for i, prefix := range d.prefer {
+		if prefix.Contains(addr) {
+			return i
+		}
+	}
+	return len(d.prefer)
+}
+
+func (d *detector) familyRank(addr netip.Addr) int {
+	if (d.family == FamilyIPv6First) == addr.Is6() {
+		return 0
+	}
+	return 1
+}
@@ -3,13 +3,26 @@ package nacos
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/go-kratos/kratos/contrib/registry/nacos/v2"
 	"github.com/go-kratos/kratos/v2/registry"
 )
 
+// metadataWeight 实例权重的元数据key，与http包MetadataWeight一致
+const metadataWeight = "weight"
+
 // RegisterServer 服务注册接口
+// kratos nacos服务发现只返回实例元数据，不返回nacos实例的Weight，需要按权重负载均衡时在元数据中设置weight，
+// 注册时会同步设置为nacos实例权重，保证nacos控制台与服务发现方看到的权重一致
 func (c *client) RegisterServer(serviceInstance *registry.ServiceInstance, opts ...nacos.Option) error {
+	if v, ok := serviceInstance.Metadata[metadataWeight]; ok {
+		weight, err := strconv.ParseFloat(v, 64)
+		if err != nil || weight <= 0 {
+			return fmt.Errorf("invalid metadata weight %s.", v)
+		}
+		opts = append(opts, nacos.WithWeight(weight))
+	}
 	if err := c.GetRegistrarClient(opts...).Register(context.Background(), serviceInstance); err != nil {
 		return fmt.Errorf("register server fail, err: %v.", err)
 	}